@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"sort"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/brendenwelch/chirpy/internal/auth"
+	"github.com/brendenwelch/chirpy/internal/chirptext"
 	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/brendenwelch/chirpy/internal/mailer"
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 )
@@ -22,6 +27,26 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	})
 }
 
+// authenticate returns the ID of the user whose access token is on the request.
+func (cfg *apiConfig) authenticate(req *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return auth.ValidateJWT(token, cfg.secret)
+}
+
+// viewer returns the ID of the user making the request if they sent a valid
+// access token. Endpoints that can be read anonymously use it to personalise
+// their responses.
+func (cfg *apiConfig) viewer(req *http.Request) uuid.NullUUID {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
 func (cfg *apiConfig) handlerMetrics(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
@@ -52,11 +77,23 @@ func (cfg *apiConfig) handlerUsers(w http.ResponseWriter, req *http.Request) {
 	params := struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}{}
 	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
 		respondWithError(w, 400, "Failed to decode request")
 		return
 	}
+	if !mailer.ValidAddress(params.Email) {
+		respondWithError(w, 400, "Invalid email address")
+		return
+	}
+	handle := strings.ToLower(params.Handle)
+	if handle == "" {
+		handle = defaultHandle()
+	} else if !chirptext.ValidHandle(handle) {
+		respondWithError(w, 400, "Handles must be 3-15 letters, digits or underscores")
+		return
+	}
 
 	hashed, err := auth.HashPassword(params.Password)
 	if err != nil {
@@ -67,24 +104,37 @@ func (cfg *apiConfig) handlerUsers(w http.ResponseWriter, req *http.Request) {
 	user, err := cfg.db.CreateUser(req.Context(), database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hashed,
+		Handle:         handle,
 	})
 	if err != nil {
 		respondWithError(w, 400, "Failed to create user")
 		return
 	}
+	// The account is usable without a verified address, so failing to send
+	// the email isn't fatal. It can be sent again later.
+	token, err := createEmailVerification(req.Context(), cfg.db, user.ID, user.Email)
+	if err != nil {
+		log.Printf("Failed to create email verification: %v", err)
+	} else {
+		cfg.sendEmailVerification(user.Email, token)
+	}
 
 	respondWithJSON(w, http.StatusCreated, struct {
-		ID          uuid.UUID `json:"id"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		Email       string    `json:"email"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
+		ID            uuid.UUID `json:"id"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		EmailVerified bool      `json:"email_verified"`
+		Handle        string    `json:"handle"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"`
 	}{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Handle:        user.Handle,
+		IsChirpyRed:   user.IsChirpyRed,
 	})
 }
 
@@ -103,39 +153,111 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, req *http.Request
 	params := struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}{}
 	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
 		respondWithError(w, 400, "Failed to decode request")
 		return
 	}
+	if !mailer.ValidAddress(params.Email) {
+		respondWithError(w, 400, "Invalid email address")
+		return
+	}
+	handle := strings.ToLower(params.Handle)
+	if handle != "" && !chirptext.ValidHandle(handle) {
+		respondWithError(w, 400, "Handles must be 3-15 letters, digits or underscores")
+		return
+	}
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, 400, "Failed to hash password")
 		return
 	}
 
-	user, err := cfg.db.UpdateUser(req.Context(), database.UpdateUserParams{
+	current, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	// A new address only replaces the current one once it's verified.
+	pendingEmail := ""
+	if params.Email != current.Email {
+		_, err := cfg.db.GetUserByEmail(req.Context(), params.Email)
+		if err == nil {
+			respondWithError(w, 400, "Email address is already in use")
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, 400, "Failed to update user")
+			return
+		}
+		pendingEmail = params.Email
+	}
+
+	// The password, handle and pending email change together or not at all,
+	// so a taken handle doesn't leave the password half-updated.
+	tx, err := cfg.sqlDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		respondWithError(w, 400, "Failed to update user")
+		return
+	}
+	defer tx.Rollback()
+	q := cfg.db.WithTx(tx)
+
+	user, err := q.UpdateUser(req.Context(), database.UpdateUserParams{
 		ID:             userID,
-		Email:          params.Email,
 		HashedPassword: hashedPassword,
 	})
 	if err != nil {
 		respondWithError(w, 400, "Failed to update user")
 		return
 	}
+	if handle != "" && handle != user.Handle {
+		user, err = q.SetUserHandle(req.Context(), database.SetUserHandleParams{
+			ID:     userID,
+			Handle: handle,
+		})
+		if err != nil {
+			respondWithError(w, 400, "Failed to update handle. Is it already taken?")
+			return
+		}
+	}
+	var verificationToken string
+	if pendingEmail != "" {
+		verificationToken, err = createEmailVerification(req.Context(), q, userID, pendingEmail)
+		if err != nil {
+			respondWithError(w, 400, "Failed to update user")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 400, "Failed to update user")
+		return
+	}
+	// Mail only once the change is stored. A failed send doesn't undo it;
+	// the user can ask for the change again to get a new token.
+	if pendingEmail != "" {
+		cfg.sendEmailVerification(pendingEmail, verificationToken)
+	}
 
 	respondWithJSON(w, http.StatusOK, struct {
-		ID          uuid.UUID `json:"id"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		Email       string    `json:"email"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
+		ID            uuid.UUID `json:"id"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		EmailVerified bool      `json:"email_verified"`
+		PendingEmail  string    `json:"pending_email,omitempty"`
+		Handle        string    `json:"handle"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"`
 	}{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		PendingEmail:  pendingEmail,
+		Handle:        user.Handle,
+		IsChirpyRed:   user.IsChirpyRed,
 	})
 }
 
@@ -160,7 +282,21 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	tokenDuration := time.Duration(params.ExpiresInSeconds)
+	mfaEnabled, err := cfg.mfaEnabled(req.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 400, "Failed to log in")
+		return
+	}
+	if mfaEnabled {
+		cfg.respondWithMFAChallenge(w, user.ID)
+		return
+	}
+	cfg.logIn(w, req, user, params.ExpiresInSeconds)
+}
+
+// logIn starts a new session for user and responds with its tokens.
+func (cfg *apiConfig) logIn(w http.ResponseWriter, req *http.Request, user database.User, expiresInSeconds int) {
+	tokenDuration := time.Duration(expiresInSeconds)
 	if tokenDuration < time.Second || tokenDuration > time.Hour {
 		tokenDuration = time.Hour
 	}
@@ -175,9 +311,13 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 		respondWithError(w, 400, "Failed to create refresh token")
 		return
 	}
+	userAgent, ip := clientInfo(req)
 	refreshToken, err := cfg.db.CreateRefreshToken(req.Context(), database.CreateRefreshTokenParams{
-		Token:  refreshTokenString,
-		UserID: user.ID,
+		Token:     refreshTokenString,
+		UserID:    user.ID,
+		FamilyID:  uuid.New(),
+		UserAgent: userAgent,
+		Ip:        ip,
 	})
 	if err != nil {
 		respondWithError(w, 400, "Failed to add refresh token to database")
@@ -185,21 +325,27 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, req *http.Request) {
 	}
 
 	respondWithJSON(w, http.StatusOK, struct {
-		ID           uuid.UUID `json:"id"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		Email        string    `json:"email"`
-		IsChirpyRed  bool      `json:"is_chirpy_red"`
-		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
+		ID            uuid.UUID `json:"id"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		EmailVerified bool      `json:"email_verified"`
+		Handle        string    `json:"handle"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"`
+		Token         string    `json:"token"`
+		RefreshToken  string    `json:"refresh_token"`
+		SessionID     uuid.UUID `json:"session_id"`
 	}{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
-		IsChirpyRed:  user.IsChirpyRed,
-		Token:        token,
-		RefreshToken: refreshToken.Token,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Handle:        user.Handle,
+		IsChirpyRed:   user.IsChirpyRed,
+		Token:         token,
+		RefreshToken:  refreshToken.Token,
+		SessionID:     refreshToken.FamilyID,
 	})
 }
 
@@ -214,12 +360,32 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, req *http.Request) {
 		respondWithError(w, 401, "Refresh token not in database")
 		return
 	}
-	if refreshToken.ExpiresAt.Before(time.Now()) {
-		respondWithError(w, 401, "Refresh token expired")
+	err = auth.CheckRefreshToken(time.Now().UTC(), refreshToken.ExpiresAt, refreshToken.RevokedAt, refreshToken.RotatedAt)
+	if errors.Is(err, auth.ErrRefreshTokenReused) {
+		cfg.revokeRefreshTokenFamily(req.Context(), refreshToken)
+	}
+	if err != nil {
+		respondWithError(w, 401, "Invalid refresh token: "+err.Error())
 		return
 	}
-	if refreshToken.RevokedAt.Valid {
-		respondWithError(w, 401, "Refresh token revoked")
+
+	userAgent, ip := clientInfo(req)
+	newRefreshToken, err := cfg.rotateRefreshToken(req.Context(), refreshToken, userAgent, ip)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The token changed since we read it. If another request rotated it
+		// first, that is reuse: each token is only ever sent once by its
+		// owner. If it was revoked or expired instead, just refuse it.
+		current, getErr := cfg.db.GetRefreshToken(req.Context(), token)
+		if getErr == nil && current.RotatedAt.Valid {
+			cfg.revokeRefreshTokenFamily(req.Context(), current)
+			respondWithError(w, 401, "Invalid refresh token: "+auth.ErrRefreshTokenReused.Error())
+			return
+		}
+		respondWithError(w, 401, "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, "Failed to rotate refresh token")
 		return
 	}
 
@@ -230,12 +396,57 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, req *http.Request) {
 	}
 
 	respondWithJSON(w, http.StatusOK, struct {
-		Token string `json:"token"`
+		Token        string    `json:"token"`
+		RefreshToken string    `json:"refresh_token"`
+		SessionID    uuid.UUID `json:"session_id"`
 	}{
-		Token: accessToken,
+		Token:        accessToken,
+		RefreshToken: newRefreshToken.Token,
+		SessionID:    newRefreshToken.FamilyID,
 	})
 }
 
+// rotateRefreshToken replaces old with a new token in the same family, used
+// by the device described by userAgent and ip. Old is only given up if its
+// replacement is stored, so a failed refresh never logs the user out.
+func (cfg *apiConfig) rotateRefreshToken(ctx context.Context, old database.RefreshToken, userAgent, ip string) (database.RefreshToken, error) {
+	tokenString, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.RefreshToken{}, err
+	}
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.RefreshToken{}, err
+	}
+	defer tx.Rollback()
+	q := cfg.db.WithTx(tx)
+
+	if _, err := q.RotateRefreshToken(ctx, old.Token); err != nil {
+		return database.RefreshToken{}, err
+	}
+	refreshToken, err := q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:       tokenString,
+		UserID:      old.UserID,
+		FamilyID:    old.FamilyID,
+		ParentToken: sql.NullString{String: old.Token, Valid: true},
+		UserAgent:   userAgent,
+		Ip:          ip,
+	})
+	if err != nil {
+		return database.RefreshToken{}, err
+	}
+	return refreshToken, tx.Commit()
+}
+
+// revokeRefreshTokenFamily logs out every session descended from the same
+// login as refreshToken.
+func (cfg *apiConfig) revokeRefreshTokenFamily(ctx context.Context, refreshToken database.RefreshToken) {
+	log.Printf("Refresh token reused, revoking family %v of user %v", refreshToken.FamilyID, refreshToken.UserID)
+	if err := cfg.db.RevokeRefreshTokenFamily(ctx, refreshToken.FamilyID); err != nil {
+		log.Printf("Failed to revoke refresh token family %v: %v", refreshToken.FamilyID, err)
+	}
+}
+
 func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, req *http.Request) {
 	token, err := auth.GetBearerToken(req.Header)
 	if err != nil {
@@ -298,55 +509,220 @@ func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, req *http.Reques
 		respondWithError(w, http.StatusUnauthorized, "Invalid user. Try logging in again: "+err.Error())
 		return
 	}
+	if err := cfg.checkEmailVerified(req.Context(), userID); err != nil {
+		respondWithEmailVerifiedError(w, err)
+		return
+	}
 
 	params := struct {
-		Body string `json:"body"`
+		Body       string        `json:"body"`
+		InReplyTo  uuid.NullUUID `json:"in_reply_to"`
+		QuoteOf    uuid.NullUUID `json:"quote_of"`
+		MediaIDs   []uuid.UUID   `json:"media_ids"`
+		Poll       *pollParams   `json:"poll"`
+		PublishAt  *time.Time    `json:"publish_at"`
+		Visibility string        `json:"visibility"`
 	}{}
 	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
 		respondWithError(w, 400, "Failed to decode request")
 		return
 	}
+	visibility, ok := parseVisibility(params.Visibility)
+	if !ok {
+		respondWithError(w, 400, "Visibility must be public, followers or mentioned")
+		return
+	}
 
-	profanes := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
+	if params.PublishAt != nil {
+		if params.InReplyTo.Valid || params.QuoteOf.Valid || len(params.MediaIDs) > 0 || params.Poll != nil {
+			respondWithError(w, 400, "Scheduled chirps can only have a body")
+			return
+		}
+		cleaned, err := cleanChirpBody(params.Body)
+		if err != nil {
+			respondWithError(w, 400, "Chirp is too long")
+			return
+		}
+		cfg.scheduleChirp(w, req, userID, cleaned, visibility, *params.PublishAt)
+		return
 	}
-	words := strings.Split(params.Body, " ")
-	for i, word := range words {
-		_, exists := profanes[strings.ToLower(word)]
-		if exists {
-			words[i] = "****"
+
+	var threadID uuid.NullUUID
+	var parentAuthorID uuid.UUID
+	if params.InReplyTo.Valid {
+		parent, err := cfg.db.GetChirp(req.Context(), params.InReplyTo.UUID)
+		if err != nil {
+			respondWithError(w, 404, "Chirp being replied to not found")
+			return
+		}
+		visible, err := cfg.canView(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, parent)
+		if err != nil {
+			respondWithError(w, 400, "Failed to create chirp")
+			return
+		}
+		if !visible {
+			respondWithError(w, 404, "Chirp being replied to not found")
+			return
+		}
+		if parent.DeletedAt.Valid {
+			respondWithError(w, 400, "Cannot reply to a deleted chirp")
+			return
+		}
+		parentAuthorID = parent.UserID
+		blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+			UserAID: userID,
+			UserBID: parentAuthorID,
+		})
+		if err != nil {
+			respondWithError(w, 400, "Failed to create chirp")
+			return
+		}
+		if blocked {
+			respondWithError(w, http.StatusForbidden, "Cannot reply to this user")
+			return
+		}
+		threadID = parent.ThreadID
+		if !threadID.Valid {
+			threadID = uuid.NullUUID{UUID: parent.ID, Valid: true}
 		}
 	}
-	cleaned := strings.Join(words, " ")
-	if len(cleaned) > 140 {
+
+	var originalID uuid.NullUUID
+	if params.QuoteOf.Valid {
+		if params.Body == "" {
+			respondWithError(w, 400, "Quote chirps need a body")
+			return
+		}
+		original, err := cfg.resolveOriginal(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, params.QuoteOf.UUID)
+		if err != nil {
+			respondWithError(w, 404, "Quoted chirp not found")
+			return
+		}
+		if original.Visibility != visibilityPublic {
+			respondWithError(w, 400, "Only public chirps can be quoted")
+			return
+		}
+		blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+			UserAID: userID,
+			UserBID: original.UserID,
+		})
+		if err != nil {
+			respondWithError(w, 400, "Failed to create chirp")
+			return
+		}
+		if blocked {
+			respondWithError(w, http.StatusForbidden, "Cannot quote this user")
+			return
+		}
+		originalID = uuid.NullUUID{UUID: original.ID, Valid: true}
+	}
+
+	cleaned, err := cleanChirpBody(params.Body)
+	if err != nil {
 		respondWithError(w, 400, "Chirp is too long")
 		return
 	}
+	if len(params.MediaIDs) > 0 {
+		if err := cfg.validateChirpMedia(req.Context(), userID, params.MediaIDs); err != nil {
+			respondWithError(w, 400, "Invalid media: "+err.Error())
+			return
+		}
+	}
+	if params.Poll != nil {
+		if err := cfg.validatePoll(req.Context(), userID, params.Poll); err != nil {
+			respondWithError(w, 400, "Invalid poll: "+err.Error())
+			return
+		}
+	}
 
 	chirp, err := cfg.db.CreateChirp(req.Context(), database.CreateChirpParams{
-		Body:   cleaned,
-		UserID: userID,
+		Body:       cleaned,
+		UserID:     userID,
+		ParentID:   params.InReplyTo,
+		ThreadID:   threadID,
+		OriginalID: originalID,
+		Visibility: visibility,
 	})
 	if err != nil {
 		respondWithError(w, 400, "Failed to create chirp")
 		return
 	}
+	if len(params.MediaIDs) > 0 {
+		err := cfg.db.CreateChirpAttachments(req.Context(), database.CreateChirpAttachmentsParams{
+			ChirpID:  chirp.ID,
+			MediaIds: params.MediaIDs,
+		})
+		if err != nil {
+			// Someone attached the same media in the meantime.
+			if err := cfg.db.DeleteChirp(req.Context(), chirp.ID); err != nil {
+				log.Printf("Failed to remove chirp %v: %v", chirp.ID, err)
+			}
+			respondWithError(w, 400, "Failed to attach media")
+			return
+		}
+	}
+	if params.Poll != nil {
+		if err := cfg.createPoll(req.Context(), chirp.ID, *params.Poll); err != nil {
+			if err := cfg.db.DeleteChirp(req.Context(), chirp.ID); err != nil {
+				log.Printf("Failed to remove chirp %v: %v", chirp.ID, err)
+			}
+			respondWithError(w, 400, "Failed to create poll")
+			return
+		}
+	}
+	mentioned, err := indexChirp(req.Context(), cfg.db, chirp)
+	if err != nil {
+		log.Printf("Failed to index chirp %v: %v", chirp.ID, err)
+	}
+	cfg.publishChirp(req.Context(), chirp)
+	for _, mentionedID := range mentioned {
+		cfg.notify(req.Context(), mentionedID, userID, notificationMention, chirp.ID)
+	}
+	if params.InReplyTo.Valid {
+		cfg.notify(req.Context(), parentAuthorID, userID, notificationReply, chirp.ID)
+	}
 
-	respondWithJSON(w, http.StatusCreated, struct {
-		ID        uuid.UUID `json:"id"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Body      string    `json:"body"`
-		UserID    uuid.UUID `json:"user_id"`
-	}{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	})
+	payload, err := cfg.chirpPayload(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load chirp")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, payload)
+}
+
+// cleanChirpBody censors profane words and enforces the chirp length limit.
+func cleanChirpBody(body string) (string, error) {
+	cleaned := censorProfanity(body)
+	if len(cleaned) > 140 {
+		return "", errors.New("chirp is too long")
+	}
+	return cleaned, nil
+}
+
+func censorProfanity(body string) string {
+	profanes := map[string]struct{}{
+		"kerfuffle": {},
+		"sharbert":  {},
+		"fornax":    {},
+	}
+	words := strings.Split(body, " ")
+	for i, word := range words {
+		_, exists := profanes[strings.ToLower(word)]
+		if exists {
+			words[i] = "****"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// indexChirp records the hashtags and mentions in a chirp's body, replacing
+// any recorded for an earlier version of it. It returns the IDs of the users
+// mentioned. q may be bound to the transaction that wrote the chirp.
+func indexChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) ([]uuid.UUID, error) {
+	if err := tagChirp(ctx, q, chirp); err != nil {
+		return nil, err
+	}
+	return mentionChirp(ctx, q, chirp)
 }
 
 func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Request) {
@@ -367,7 +743,7 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Reques
 		return
 	}
 	chirp, err := cfg.db.GetChirp(req.Context(), chirpID)
-	if err != nil {
+	if err != nil || chirp.DeletedAt.Valid {
 		respondWithError(w, 404, "Chirp not found")
 		return
 	}
@@ -376,7 +752,7 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
-	err = cfg.db.DeleteChirp(req.Context(), chirp.ID)
+	err = cfg.deleteChirp(req.Context(), chirp)
 	if err != nil {
 		respondWithError(w, 400, "Failed to delete chirp")
 		return
@@ -385,53 +761,303 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Reques
 }
 
 func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, req *http.Request) {
-	var chirps []database.Chirp
+	var authorID uuid.NullUUID
 	authorIDString := req.URL.Query().Get("author_id")
-	if authorIDString == "" {
-		var err error
-		chirps, err = cfg.db.GetAllChirps(req.Context())
+	if authorIDString != "" {
+		id, err := uuid.Parse(authorIDString)
 		if err != nil {
-			respondWithError(w, 400, "Failed to get chirps")
+			respondWithError(w, 400, "Invalid chirp author id")
 			return
 		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+	viewerID := cfg.viewer(req)
+
+	var chirps []database.Chirp
+	sortString := req.URL.Query().Get("sort")
+	if sortString == "desc" {
+		chirps, err = cfg.db.ListChirpsDesc(req.Context(), database.ListChirpsDescParams{
+			AuthorID:        authorID,
+			ViewerID:        viewerID,
+			CursorCreatedAt: p.cursorCreatedAt,
+			CursorID:        p.cursorID,
+			Limit:           p.fetchLimit(),
+		})
 	} else {
-		authorID, err := uuid.Parse(authorIDString)
+		chirps, err = cfg.db.ListChirpsAsc(req.Context(), database.ListChirpsAscParams{
+			AuthorID:        authorID,
+			ViewerID:        viewerID,
+			CursorCreatedAt: p.cursorCreatedAt,
+			CursorID:        p.cursorID,
+			Limit:           p.fetchLimit(),
+		})
+	}
+	if err != nil {
+		respondWithError(w, 400, "Failed to get chirps")
+		return
+	}
+	chirps, more := trimPage(chirps, p)
+
+	payloads, err := cfg.chirpPayloads(req.Context(), viewerID, chirps)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load chirps")
+		return
+	}
+	if authorID.Valid && req.URL.Query().Get("include_pinned") == "true" {
+		// Pinned chirps lead the first page and are left out of the
+		// rest, so they only show up once.
+		pinned, err := cfg.db.ListPinnedChirps(req.Context(), database.ListPinnedChirpsParams{
+			UserID:   authorID.UUID,
+			ViewerID: viewerID,
+		})
 		if err != nil {
-			respondWithError(w, 400, "Invalid chirp author id")
+			respondWithError(w, 400, "Failed to get pinned chirps")
 			return
 		}
-		chirps, err = cfg.db.GetAllChirpsByUser(req.Context(), authorID)
+		isPinned := make(map[uuid.UUID]bool, len(pinned))
+		for _, chirp := range pinned {
+			isPinned[chirp.ID] = true
+		}
+		payloads = slices.DeleteFunc(payloads, func(chirp Chirp) bool {
+			return isPinned[chirp.ID]
+		})
+		if !p.cursorCreatedAt.Valid {
+			pinnedPayloads, err := cfg.chirpPayloads(req.Context(), viewerID, pinned)
+			if err != nil {
+				respondWithError(w, 400, "Failed to load pinned chirps")
+				return
+			}
+			for i := range pinnedPayloads {
+				pinnedPayloads[i].Pinned = true
+			}
+			payloads = append(pinnedPayloads, payloads...)
+		}
+	}
+	if more {
+		last := chirps[len(chirps)-1]
+		setNextLink(w, req, encodeCursor(last.CreatedAt, last.ID))
+	}
+	respondWithJSON(w, http.StatusOK, payloads)
+}
+
+type Chirp struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Body      string        `json:"body"`
+	UserID    uuid.UUID     `json:"user_id"`
+	Author    *author       `json:"author,omitempty"`
+	InReplyTo uuid.NullUUID `json:"in_reply_to"`
+	ThreadID  uuid.NullUUID `json:"thread_id"`
+	LikeCount int64         `json:"like_count"`
+	LikedByMe *bool         `json:"liked_by_me,omitempty"`
+	// OriginalID is set on rechirps, which have no body of their own, and on
+	// quote chirps. Original embeds that chirp, or a tombstone of it.
+	OriginalID uuid.NullUUID `json:"original_id"`
+	IsRechirp  bool          `json:"is_rechirp"`
+	Original   *Chirp        `json:"original,omitempty"`
+	Deleted    bool          `json:"deleted"`
+	Visibility string        `json:"visibility"`
+	Mentions   []mention     `json:"mentions"`
+	Media      []mediaFile   `json:"media"`
+	Poll       *poll         `json:"poll,omitempty"`
+	// Pinned is only reported when listing an author's chirps with
+	// include_pinned.
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+func newChirp(chirp database.Chirp) Chirp {
+	return Chirp{
+		ID:         chirp.ID,
+		CreatedAt:  chirp.CreatedAt,
+		UpdatedAt:  chirp.UpdatedAt,
+		Body:       chirp.Body,
+		UserID:     chirp.UserID,
+		InReplyTo:  chirp.ParentID,
+		ThreadID:   chirp.ThreadID,
+		OriginalID: chirp.OriginalID,
+		IsRechirp:  chirp.IsRechirp,
+		Deleted:    chirp.DeletedAt.Valid,
+		Visibility: chirp.Visibility,
+	}
+}
+
+type chirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// chirpPayloads converts chirps into their JSON form. Anything aggregated from
+// other tables is loaded with one query for the whole batch, never per chirp.
+// The viewer, if any, decides the per-user fields such as liked_by_me.
+func (cfg *apiConfig) chirpPayloads(ctx context.Context, viewerID uuid.NullUUID, chirps []database.Chirp) ([]Chirp, error) {
+	payloads, err := cfg.chirpPayloadsWithoutOriginals(ctx, viewerID, chirps)
+	if err != nil {
+		return nil, err
+	}
+
+	var originalIDs []uuid.UUID
+	for _, chirp := range chirps {
+		if chirp.OriginalID.Valid {
+			originalIDs = append(originalIDs, chirp.OriginalID.UUID)
+		}
+	}
+	if len(originalIDs) == 0 {
+		return payloads, nil
+	}
+	originals, err := cfg.db.GetChirpsByIDs(ctx, originalIDs)
+	if err != nil {
+		return nil, err
+	}
+	if viewerID.Valid {
+		// Rechirps can't be used to show someone the chirps of a user
+		// they have a block with.
+		blockedIDs, err := cfg.db.ListBlockedUserIDs(ctx, viewerID.UUID)
 		if err != nil {
-			respondWithError(w, 400, "Failed to get chirps")
-			return
+			return nil, err
 		}
+		originals = slices.DeleteFunc(originals, func(original database.Chirp) bool {
+			return slices.Contains(blockedIDs, original.UserID)
+		})
+	}
+	// Originals are only embedded one level deep, so a quote of a quote
+	// shows the chirp it quoted but not what that one quoted in turn.
+	originalPayloads, err := cfg.chirpPayloadsWithoutOriginals(ctx, viewerID, originals)
+	if err != nil {
+		return nil, err
 	}
+	byID := make(map[uuid.UUID]*Chirp, len(originals))
+	for i := range originalPayloads {
+		byID[originalPayloads[i].ID] = &originalPayloads[i]
+	}
+	for i, chirp := range chirps {
+		if !chirp.OriginalID.Valid {
+			continue
+		}
+		payloads[i].Original = byID[chirp.OriginalID.UUID]
+		// Don't point at an original that was left out.
+		if payloads[i].Original == nil {
+			payloads[i].OriginalID = uuid.NullUUID{}
+		}
+	}
+	return payloads, nil
+}
 
-	sortString := req.URL.Query().Get("sort")
-	if sortString == "desc" {
-		sort.Slice(chirps, func(i int, j int) bool {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
+func (cfg *apiConfig) chirpPayloadsWithoutOriginals(ctx context.Context, viewerID uuid.NullUUID, chirps []database.Chirp) ([]Chirp, error) {
+	payloads := make([]Chirp, len(chirps))
+	if len(chirps) == 0 {
+		return payloads, nil
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	counts, err := cfg.db.CountChirpLikes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	likeCounts := make(map[uuid.UUID]int64, len(counts))
+	for _, count := range counts {
+		likeCounts[count.ChirpID] = count.LikeCount
+	}
+
+	var liked map[uuid.UUID]bool
+	if viewerID.Valid {
+		likedIDs, err := cfg.db.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{
+			UserID:   viewerID.UUID,
+			ChirpIds: ids,
 		})
+		if err != nil {
+			return nil, err
+		}
+		liked = make(map[uuid.UUID]bool, len(likedIDs))
+		for _, id := range likedIDs {
+			liked[id] = true
+		}
 	}
 
-	type Chirp struct {
-		ID        uuid.UUID `json:"id"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Body      string    `json:"body"`
-		UserID    uuid.UUID `json:"user_id"`
+	authors, err := cfg.listAuthors(ctx, chirps)
+	if err != nil {
+		return nil, err
 	}
-	var payload []Chirp
-	for _, chirp := range chirps {
-		payload = append(payload, Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
+
+	attachments, err := cfg.db.ListChirpAttachments(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	media := make(map[uuid.UUID][]mediaFile, len(attachments))
+	for _, row := range attachments {
+		media[row.ChirpID] = append(media[row.ChirpID], newMediaFile(row.MediaFile))
+	}
+
+	polls, err := cfg.listPolls(ctx, viewerID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	mentionRows, err := cfg.db.ListChirpMentions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	mentions := make(map[uuid.UUID][]mention, len(mentionRows))
+	for _, row := range mentionRows {
+		mentions[row.ChirpID] = append(mentions[row.ChirpID], mention{
+			UserID: row.UserID,
+			Handle: row.Handle,
 		})
 	}
-	respondWithJSON(w, http.StatusOK, payload)
+
+	for i, chirp := range chirps {
+		payloads[i] = newChirp(chirp)
+		payloads[i].Author = authors[chirp.UserID]
+		payloads[i].LikeCount = likeCounts[chirp.ID]
+		payloads[i].Mentions = mentions[chirp.ID]
+		if payloads[i].Mentions == nil {
+			payloads[i].Mentions = []mention{}
+		}
+		// Attachments outlive a tombstoned chirp but are no longer shown.
+		payloads[i].Media = media[chirp.ID]
+		if payloads[i].Media == nil || chirp.DeletedAt.Valid {
+			payloads[i].Media = []mediaFile{}
+		}
+		if !chirp.DeletedAt.Valid {
+			payloads[i].Poll = polls[chirp.ID]
+		}
+		if viewerID.Valid {
+			likedByMe := liked[chirp.ID]
+			payloads[i].LikedByMe = &likedByMe
+		}
+	}
+	return payloads, nil
+}
+
+func (cfg *apiConfig) chirpPayload(ctx context.Context, viewerID uuid.NullUUID, chirp database.Chirp) (Chirp, error) {
+	payloads, err := cfg.chirpPayloads(ctx, viewerID, []database.Chirp{chirp})
+	if err != nil {
+		return Chirp{}, err
+	}
+	return payloads[0], nil
+}
+
+func (cfg *apiConfig) newChirpPage(ctx context.Context, viewerID uuid.NullUUID, chirps []database.Chirp, more bool) (chirpPage, error) {
+	payloads, err := cfg.chirpPayloads(ctx, viewerID, chirps)
+	if err != nil {
+		return chirpPage{}, err
+	}
+	payload := chirpPage{
+		Chirps: payloads,
+	}
+	if more {
+		last := chirps[len(chirps)-1]
+		payload.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	return payload, nil
 }
 
 func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, req *http.Request) {
@@ -441,23 +1067,40 @@ func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, req *http.Request)
 		return
 	}
 	chirp, err := cfg.db.GetChirp(req.Context(), id)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Failed to get chirp")
+		return
+	}
+	viewerID := cfg.viewer(req)
+	visible, err := cfg.canView(req.Context(), viewerID, chirp)
 	if err != nil {
+		respondWithError(w, 400, "Failed to get chirp")
+		return
+	}
+	if !visible {
 		respondWithError(w, 404, "Failed to get chirp")
 		return
 	}
-	respondWithJSON(w, http.StatusOK, struct {
-		ID        uuid.UUID `json:"id"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Body      string    `json:"body"`
-		UserID    uuid.UUID `json:"user_id"`
-	}{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	})
+	if viewerID.Valid {
+		blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+			UserAID: viewerID.UUID,
+			UserBID: chirp.UserID,
+		})
+		if err != nil {
+			respondWithError(w, 400, "Failed to get chirp")
+			return
+		}
+		if blocked {
+			respondWithError(w, 404, "Failed to get chirp")
+			return
+		}
+	}
+	payload, err := cfg.chirpPayload(req.Context(), viewerID, chirp)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load chirp")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, payload)
 }
 
 func respondWithError(w http.ResponseWriter, code int, msg string) {
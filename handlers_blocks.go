@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerBlock blocks another user. Any follows between the two are removed,
+// and from then on neither can follow, reply to, mention or message the
+// other, or see the other's chirps.
+func (cfg *apiConfig) handlerBlock(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	blockedID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID")
+		return
+	}
+	if blockedID == userID {
+		respondWithError(w, 400, "Cannot block yourself")
+		return
+	}
+	if _, err := cfg.db.GetUser(req.Context(), blockedID); err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	err = cfg.db.CreateBlock(req.Context(), database.CreateBlockParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to block user")
+		return
+	}
+	err = cfg.db.DeleteFollowsBetween(req.Context(), database.DeleteFollowsBetweenParams{
+		UserAID: userID,
+		UserBID: blockedID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to remove follows")
+		return
+	}
+	cfg.relationshipsChanged(userID, blockedID)
+	respondWithJSON(w, 204, struct{}{})
+}
+
+func (cfg *apiConfig) handlerUnblock(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	blockedID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID")
+		return
+	}
+
+	err = cfg.db.DeleteBlock(req.Context(), database.DeleteBlockParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to unblock user")
+		return
+	}
+	cfg.relationshipsChanged(userID, blockedID)
+	respondWithJSON(w, 204, struct{}{})
+}
+
+// handlerMute hides another user's chirps from the muter's own listings.
+// Unlike a block, the muted user can't tell and nothing else changes.
+func (cfg *apiConfig) handlerMute(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	mutedID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID")
+		return
+	}
+	if mutedID == userID {
+		respondWithError(w, 400, "Cannot mute yourself")
+		return
+	}
+	if _, err := cfg.db.GetUser(req.Context(), mutedID); err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	err = cfg.db.CreateMute(req.Context(), database.CreateMuteParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to mute user")
+		return
+	}
+	cfg.relationshipsChanged(userID)
+	respondWithJSON(w, 204, struct{}{})
+}
+
+func (cfg *apiConfig) handlerUnmute(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	mutedID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID")
+		return
+	}
+
+	err = cfg.db.DeleteMute(req.Context(), database.DeleteMuteParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to unmute user")
+		return
+	}
+	cfg.relationshipsChanged(userID)
+	respondWithJSON(w, 204, struct{}{})
+}
+
+// relationshipsChanged tells the live streams of userIDs to reload who they
+// follow, have blocked, been blocked by and muted.
+func (cfg *apiConfig) relationshipsChanged(userIDs ...uuid.UUID) {
+	for _, id := range userIDs {
+		cfg.relationshipStream.Publish(id)
+	}
+}
+
+// hiddenAuthorSet loads the IDs of the users whose chirps userID never sees:
+// those blocked either way, and those userID muted.
+func (cfg *apiConfig) hiddenAuthorSet(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+	blockedIDs, err := cfg.db.ListBlockedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	mutedIDs, err := cfg.db.ListMutedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	hidden := make(map[uuid.UUID]bool, len(blockedIDs)+len(mutedIDs))
+	for _, id := range slices.Concat(blockedIDs, mutedIDs) {
+		hidden[id] = true
+	}
+	return hidden, nil
+}
+
+type restrictedUser struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type restrictedUserPage struct {
+	Users      []restrictedUser `json:"users"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+func newRestrictedUserPage(users []restrictedUser, more bool) restrictedUserPage {
+	payload := restrictedUserPage{
+		Users: append([]restrictedUser{}, users...),
+	}
+	if more {
+		last := users[len(users)-1]
+		payload.NextCursor = encodeCursor(last.CreatedAt, last.UserID)
+	}
+	return payload
+}
+
+func (cfg *apiConfig) handlerGetBlocks(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	blocks, err := cfg.db.ListBlocks(req.Context(), database.ListBlocksParams{
+		UserID:          userID,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get blocked users")
+		return
+	}
+	blocks, more := trimPage(blocks, p)
+
+	users := make([]restrictedUser, len(blocks))
+	for i, block := range blocks {
+		users[i] = restrictedUser{UserID: block.BlockedID, CreatedAt: block.CreatedAt}
+	}
+	respondWithJSON(w, http.StatusOK, newRestrictedUserPage(users, more))
+}
+
+func (cfg *apiConfig) handlerGetMutes(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	mutes, err := cfg.db.ListMutes(req.Context(), database.ListMutesParams{
+		UserID:          userID,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get muted users")
+		return
+	}
+	mutes, more := trimPage(mutes, p)
+
+	users := make([]restrictedUser, len(mutes))
+	for i, mute := range mutes {
+		users[i] = restrictedUser{UserID: mute.MutedID, CreatedAt: mute.CreatedAt}
+	}
+	respondWithJSON(w, http.StatusOK, newRestrictedUserPage(users, more))
+}
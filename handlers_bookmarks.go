@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxPinnedChirps = 3
+
+var errTooManyPins = errors.New("too many pinned chirps")
+
+// handlerBookmark saves a chirp for later. Bookmarks are private: nobody else,
+// including the author, can see them.
+func (cfg *apiConfig) handlerBookmark(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+	chirp, err := cfg.db.GetChirp(req.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	visible, err := cfg.canView(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		respondWithError(w, 400, "Failed to bookmark chirp")
+		return
+	}
+	if !visible {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+		UserAID: userID,
+		UserBID: chirp.UserID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to bookmark chirp")
+		return
+	}
+	if blocked {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+
+	err = cfg.db.CreateBookmark(req.Context(), database.CreateBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to bookmark chirp")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
+
+func (cfg *apiConfig) handlerUnbookmark(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+
+	err = cfg.db.DeleteBookmark(req.Context(), database.DeleteBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to remove bookmark")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
+
+// handlerGetBookmarks lists bookmarked chirps, most recently bookmarked first.
+func (cfg *apiConfig) handlerGetBookmarks(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	rows, err := cfg.db.ListBookmarkedChirps(req.Context(), database.ListBookmarkedChirpsParams{
+		UserID:          userID,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get bookmarks")
+		return
+	}
+	rows, more := trimPage(rows, p)
+
+	chirps := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = row.Chirp
+	}
+	payloads, err := cfg.chirpPayloads(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load chirps")
+		return
+	}
+	payload := chirpPage{
+		Chirps: payloads,
+	}
+	if more {
+		last := rows[len(rows)-1]
+		payload.NextCursor = encodeCursor(last.BookmarkedAt, last.Chirp.ID)
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
+
+// handlerPin pins one of the caller's own chirps to their profile.
+func (cfg *apiConfig) handlerPin(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+	chirp, err := cfg.db.GetChirp(req.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "You can only pin your own chirps")
+		return
+	}
+
+	err = cfg.pinChirp(req.Context(), userID, chirp.ID)
+	if errors.Is(err, errTooManyPins) {
+		respondWithError(w, 400, "You can pin at most 3 chirps")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, "Failed to pin chirp")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
+
+// pinChirp pins chirpID for userID unless they already have maxPinnedChirps
+// pinned. The user's row stays locked from counting to inserting, so
+// concurrent pins can't go over the limit.
+func (cfg *apiConfig) pinChirp(ctx context.Context, userID, chirpID uuid.UUID) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := cfg.db.WithTx(tx)
+
+	if err := q.LockUser(ctx, userID); err != nil {
+		return err
+	}
+	pinned, err := q.ListPinnedChirpIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(pinned, chirpID) {
+		return nil
+	}
+	if len(pinned) >= maxPinnedChirps {
+		return errTooManyPins
+	}
+	err = q.CreatePin(ctx, database.CreatePinParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (cfg *apiConfig) handlerUnpin(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+
+	err = cfg.db.DeletePin(req.Context(), database.DeletePinParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to unpin chirp")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type draft struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+}
+
+func newDraft(d database.Draft) draft {
+	return draft{
+		ID:        d.ID,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+		Body:      d.Body,
+	}
+}
+
+// decodeDraftBody reads a draft's body, held to the same rules as a chirp's
+// so it can be posted as is.
+func decodeDraftBody(w http.ResponseWriter, req *http.Request) (string, bool) {
+	params := struct {
+		Body string `json:"body"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return "", false
+	}
+	cleaned, err := cleanChirpBody(params.Body)
+	if err != nil {
+		respondWithError(w, 400, "Draft is too long")
+		return "", false
+	}
+	return cleaned, true
+}
+
+func (cfg *apiConfig) handlerCreateDraft(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	body, ok := decodeDraftBody(w, req)
+	if !ok {
+		return
+	}
+
+	d, err := cfg.db.CreateDraft(req.Context(), database.CreateDraftParams{
+		UserID: userID,
+		Body:   body,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to create draft")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newDraft(d))
+}
+
+func (cfg *apiConfig) handlerGetDrafts(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	drafts, err := cfg.db.ListDrafts(req.Context(), database.ListDraftsParams{
+		UserID:          userID,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get drafts")
+		return
+	}
+	drafts, more := trimPage(drafts, p)
+
+	payload := struct {
+		Drafts     []draft `json:"drafts"`
+		NextCursor string  `json:"next_cursor,omitempty"`
+	}{
+		Drafts: []draft{},
+	}
+	for _, d := range drafts {
+		payload.Drafts = append(payload.Drafts, newDraft(d))
+	}
+	if more {
+		last := drafts[len(drafts)-1]
+		payload.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
+
+// handlerGetDraft, like the other single-draft handlers, reports drafts that
+// belong to someone else as missing.
+func (cfg *apiConfig) handlerGetDraft(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	id, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid draft ID")
+		return
+	}
+
+	d, err := cfg.db.GetDraft(req.Context(), database.GetDraftParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newDraft(d))
+}
+
+func (cfg *apiConfig) handlerUpdateDraft(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	id, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid draft ID")
+		return
+	}
+	body, ok := decodeDraftBody(w, req)
+	if !ok {
+		return
+	}
+
+	d, err := cfg.db.UpdateDraft(req.Context(), database.UpdateDraftParams{
+		ID:     id,
+		UserID: userID,
+		Body:   body,
+	})
+	if err != nil {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newDraft(d))
+}
+
+func (cfg *apiConfig) handlerDeleteDraft(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	id, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid draft ID")
+		return
+	}
+
+	deleted, err := cfg.db.DeleteDraft(req.Context(), database.DeleteDraftParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to delete draft")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Draft not found")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/brendenwelch/chirpy/internal/auth"
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/brendenwelch/chirpy/internal/mailer"
+	"github.com/google/uuid"
+)
+
+var errEmailNotVerified = errors.New("email address not verified")
+
+// createEmailVerification stores a token that, once confirmed, makes email
+// userID's verified address, and returns it to be mailed. Tokens created
+// earlier stop working, so only the most recently requested address can be
+// confirmed.
+func createEmailVerification(ctx context.Context, q *database.Queries, userID uuid.UUID, email string) (string, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	if err := q.DeleteEmailVerificationTokens(ctx, userID); err != nil {
+		return "", err
+	}
+	err = q.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// sendEmailVerification mails a token from createEmailVerification to email.
+func (cfg *apiConfig) sendEmailVerification(email, token string) {
+	cfg.sendMail(mailer.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Confirm this is your email address by sending Chirpy this token:\n\n%s\n\n"+
+			"It expires in 24 hours. If you don't have a Chirpy account, you can ignore this email.\n", token),
+	})
+}
+
+// checkEmailVerified returns errEmailNotVerified if unverified users aren't
+// allowed to chirp and userID hasn't verified their email address.
+func (cfg *apiConfig) checkEmailVerified(ctx context.Context, userID uuid.UUID) error {
+	if !cfg.requireVerifiedEmail {
+		return nil
+	}
+	user, err := cfg.db.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.EmailVerifiedAt.Valid {
+		return errEmailNotVerified
+	}
+	return nil
+}
+
+// respondWithEmailVerifiedError reports a failed checkEmailVerified.
+func respondWithEmailVerifiedError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errEmailNotVerified) {
+		respondWithError(w, http.StatusForbidden, "Verify your email address before chirping")
+		return
+	}
+	respondWithError(w, 400, "Failed to look up user")
+}
+
+// handlerRequestEmailVerification sends a new verification token to the
+// caller's current address, in case the first one was lost or expired.
+func (cfg *apiConfig) handlerRequestEmailVerification(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	user, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Email address is already verified")
+		return
+	}
+
+	token, err := createEmailVerification(req.Context(), cfg.db, user.ID, user.Email)
+	if err != nil {
+		respondWithError(w, 400, "Failed to send verification email")
+		return
+	}
+	cfg.sendEmailVerification(user.Email, token)
+	respondWithJSON(w, http.StatusAccepted, struct{}{})
+}
+
+// handlerConfirmEmailVerification marks an address as verified with the token
+// sent to it. For an email change, this is when the new address takes over.
+func (cfg *apiConfig) handlerConfirmEmailVerification(w http.ResponseWriter, req *http.Request) {
+	params := struct {
+		Token string `json:"token"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+
+	err := cfg.verifyEmail(req.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 400, "Invalid or expired verification token")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, "Failed to verify email address. Is it already in use?")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
+
+// verifyEmail uses up the verification token with tokenHash and makes its
+// address the user's verified email.
+func (cfg *apiConfig) verifyEmail(ctx context.Context, tokenHash string) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := cfg.db.WithTx(tx)
+
+	verification, err := q.UseEmailVerificationToken(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+	_, err = q.VerifyUserEmail(ctx, database.VerifyUserEmailParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerFollow(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	followeeID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID")
+		return
+	}
+	if followeeID == userID {
+		respondWithError(w, 400, "Cannot follow yourself")
+		return
+	}
+	if _, err := cfg.db.GetUser(req.Context(), followeeID); err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+		UserAID: userID,
+		UserBID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to follow user")
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusForbidden, "Cannot follow this user")
+		return
+	}
+
+	created, err := cfg.db.CreateFollow(req.Context(), database.CreateFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to follow user")
+		return
+	}
+	if created > 0 {
+		cfg.notify(req.Context(), followeeID, userID, notificationFollow, uuid.Nil)
+		cfg.relationshipsChanged(userID)
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
+
+func (cfg *apiConfig) handlerUnfollow(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	followeeID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID")
+		return
+	}
+
+	err = cfg.db.DeleteFollow(req.Context(), database.DeleteFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to unfollow user")
+		return
+	}
+	cfg.relationshipsChanged(userID)
+	respondWithJSON(w, 204, struct{}{})
+}
+
+type followedUser struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followPage struct {
+	Users      []followedUser `json:"users"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handlerGetFollowers(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID")
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	follows, err := cfg.db.ListFollowers(req.Context(), database.ListFollowersParams{
+		UserID:          userID,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get followers")
+		return
+	}
+	follows, more := trimPage(follows, p)
+
+	respondWithJSON(w, http.StatusOK, newFollowPage(follows, more, func(f database.Follow) uuid.UUID {
+		return f.FollowerID
+	}))
+}
+
+func (cfg *apiConfig) handlerGetFollowing(w http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID")
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	follows, err := cfg.db.ListFollowing(req.Context(), database.ListFollowingParams{
+		UserID:          userID,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get followed users")
+		return
+	}
+	follows, more := trimPage(follows, p)
+
+	respondWithJSON(w, http.StatusOK, newFollowPage(follows, more, func(f database.Follow) uuid.UUID {
+		return f.FolloweeID
+	}))
+}
+
+// newFollowPage lists the users on the other side of each follow, picked out
+// by other, and builds the cursor from that user's ID.
+func newFollowPage(follows []database.Follow, more bool, other func(database.Follow) uuid.UUID) followPage {
+	payload := followPage{
+		Users: []followedUser{},
+	}
+	for _, follow := range follows {
+		payload.Users = append(payload.Users, followedUser{
+			UserID:     other(follow),
+			FollowedAt: follow.CreatedAt,
+		})
+	}
+	if more {
+		last := follows[len(follows)-1]
+		payload.NextCursor = encodeCursor(last.CreatedAt, other(last))
+	}
+	return payload
+}
+
+func (cfg *apiConfig) handlerTimeline(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	chirps, err := cfg.db.ListTimeline(req.Context(), database.ListTimelineParams{
+		UserID:          userID,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get timeline")
+		return
+	}
+	chirps, more := trimPage(chirps, p)
+
+	payload, err := cfg.newChirpPage(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps, more)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load timeline")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
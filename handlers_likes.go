@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+	chirp, err := cfg.db.GetChirp(req.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	visible, err := cfg.canView(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		respondWithError(w, 400, "Failed to like chirp")
+		return
+	}
+	if !visible {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+
+	created, err := cfg.db.CreateChirpLike(req.Context(), database.CreateChirpLikeParams{
+		ChirpID: chirp.ID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to like chirp")
+		return
+	}
+	if created > 0 {
+		cfg.notify(req.Context(), chirp.UserID, userID, notificationLike, chirp.ID)
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
+
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+
+	err = cfg.db.DeleteChirpLike(req.Context(), database.DeleteChirpLikeParams{
+		ChirpID: chirpID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to unlike chirp")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
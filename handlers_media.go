@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/brendenwelch/chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+const maxChirpMedia = 4
+
+type mediaFile struct {
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	Size         int32     `json:"size"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+}
+
+func newMediaFile(m database.MediaFile) mediaFile {
+	return mediaFile{
+		ID:           m.ID,
+		CreatedAt:    m.CreatedAt,
+		ContentType:  m.ContentType,
+		Width:        m.Width,
+		Height:       m.Height,
+		Size:         m.Size,
+		URL:          "/media/" + m.StorageKey,
+		ThumbnailURL: "/media/" + m.ThumbnailKey,
+	}
+}
+
+// handlerUploadMedia takes an image as the "file" field of a multipart form.
+// The stored copy is re-encoded, so nothing but the pixels survives.
+func (cfg *apiConfig) handlerUploadMedia(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	// Leave some room for the rest of the multipart form.
+	req.Body = http.MaxBytesReader(w, req.Body, media.MaxUploadSize+64<<10)
+	file, _, err := req.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large")
+			return
+		}
+		respondWithError(w, 400, "Expected a multipart form with a file field")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxUploadSize+1))
+	if err != nil {
+		respondWithError(w, 400, "Failed to read file")
+		return
+	}
+
+	processed, err := media.Process(data)
+	switch {
+	case errors.Is(err, media.ErrUnsupportedType):
+		respondWithError(w, http.StatusUnsupportedMediaType, "Only JPEG, PNG and GIF images are supported")
+		return
+	case errors.Is(err, media.ErrTooLarge):
+		respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large")
+		return
+	case err != nil:
+		respondWithError(w, 400, "Failed to process image")
+		return
+	}
+
+	id := uuid.New()
+	ext := media.Extension(processed.Image.ContentType)
+	storageKey := id.String() + ext
+	thumbnailKey := id.String() + "_thumb" + ext
+	if err := cfg.media.Put(req.Context(), storageKey, processed.Image.Data); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to store image")
+		return
+	}
+	if err := cfg.media.Put(req.Context(), thumbnailKey, processed.Thumbnail.Data); err != nil {
+		cfg.deleteMediaFiles(req.Context(), storageKey)
+		respondWithError(w, http.StatusInternalServerError, "Failed to store image")
+		return
+	}
+
+	m, err := cfg.db.CreateMediaFile(req.Context(), database.CreateMediaFileParams{
+		ID:           id,
+		UserID:       userID,
+		ContentType:  processed.Image.ContentType,
+		Width:        int32(processed.Image.Width),
+		Height:       int32(processed.Image.Height),
+		Size:         int32(len(processed.Image.Data)),
+		StorageKey:   storageKey,
+		ThumbnailKey: thumbnailKey,
+	})
+	if err != nil {
+		cfg.deleteMediaFiles(req.Context(), storageKey, thumbnailKey)
+		respondWithError(w, 400, "Failed to save media")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newMediaFile(m))
+}
+
+func (cfg *apiConfig) deleteMediaFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := cfg.media.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete media %v: %v", key, err)
+		}
+	}
+}
+
+// handlerServeMedia serves stored media to whoever can read the chirp it is
+// attached to. Until it is attached, only the uploader can see it. Keys are
+// never reused, so attached media may be cached forever.
+func (cfg *apiConfig) handlerServeMedia(w http.ResponseWriter, req *http.Request) {
+	key := req.PathValue("key")
+	m, err := cfg.db.GetMediaFileByKey(req.Context(), key)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	viewerID := cfg.viewer(req)
+	cacheControl := "private, no-store"
+	if m.ChirpID.Valid {
+		chirp, err := cfg.db.GetChirp(req.Context(), m.ChirpID.UUID)
+		if err != nil || chirp.DeletedAt.Valid {
+			http.NotFound(w, req)
+			return
+		}
+		if viewerID.Valid {
+			blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+				UserAID: viewerID.UUID,
+				UserBID: chirp.UserID,
+			})
+			if err != nil || blocked {
+				http.NotFound(w, req)
+				return
+			}
+		}
+		visible, err := cfg.canView(req.Context(), viewerID, chirp)
+		if err != nil || !visible {
+			http.NotFound(w, req)
+			return
+		}
+		// Only media on public chirps may be kept by shared caches.
+		if chirp.Visibility == visibilityPublic {
+			cacheControl = "public, max-age=31536000, immutable"
+		}
+	} else if !viewerID.Valid || viewerID.UUID != m.UserID {
+		http.NotFound(w, req)
+		return
+	}
+
+	f, err := cfg.media.Open(req.Context(), key)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, req, key, time.Time{}, f)
+}
+
+// validateChirpMedia checks that userID may attach mediaIDs to a new chirp:
+// at most four of their own uploads, none of them already used.
+func (cfg *apiConfig) validateChirpMedia(ctx context.Context, userID uuid.UUID, mediaIDs []uuid.UUID) error {
+	if len(mediaIDs) > maxChirpMedia {
+		return errors.New("chirps can have at most 4 media")
+	}
+	seen := make(map[uuid.UUID]bool, len(mediaIDs))
+	for _, id := range mediaIDs {
+		if seen[id] {
+			return errors.New("media can only be attached once")
+		}
+		seen[id] = true
+	}
+	count, err := cfg.db.CountAttachableMediaFiles(ctx, database.CountAttachableMediaFilesParams{
+		Ids:    mediaIDs,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if count != int64(len(mediaIDs)) {
+		return errors.New("media not found or already attached")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/brendenwelch/chirpy/internal/chirptext"
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type mention struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+}
+
+// defaultHandle makes up a handle for users who sign up without one. It
+// matches the handles given to existing users when handles were introduced.
+func defaultHandle() string {
+	return "user_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:10]
+}
+
+// mentionChirp replaces the mentions stored for chirp with the handles
+// @mentioned in its body, and returns the IDs of the users mentioned. Handles
+// that belong to nobody, or to someone with a block either way between them
+// and the author, are ignored.
+func mentionChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) ([]uuid.UUID, error) {
+	if err := q.DeleteChirpMentions(ctx, chirp.ID); err != nil {
+		return nil, err
+	}
+	handles := chirptext.Mentions(chirp.Body)
+	if len(handles) == 0 {
+		return nil, nil
+	}
+	return q.CreateChirpMentions(ctx, database.CreateChirpMentionsParams{
+		ChirpID:  chirp.ID,
+		Handles:  handles,
+		AuthorID: chirp.UserID,
+	})
+}
+
+func (cfg *apiConfig) handlerGetMyMentions(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	chirps, err := cfg.db.ListMentioningChirps(req.Context(), database.ListMentioningChirpsParams{
+		UserID:          userID,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get mentions")
+		return
+	}
+	chirps, more := trimPage(chirps, p)
+
+	payload, err := cfg.newChirpPage(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps, more)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxMessageLength = 1000
+
+type conversation struct {
+	ID             uuid.UUID    `json:"id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	ParticipantIDs [2]uuid.UUID `json:"participant_ids"`
+}
+
+func newConversation(c database.Conversation) conversation {
+	return conversation{
+		ID:             c.ID,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+		ParticipantIDs: [2]uuid.UUID{c.UserAID, c.UserBID},
+	}
+}
+
+type message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+func newMessage(m database.Message) message {
+	return message{
+		ID:             m.ID,
+		CreatedAt:      m.CreatedAt,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+	}
+}
+
+// participantConversation gets a conversation userID takes part in. Other
+// conversations are reported as missing, so their IDs can't be probed.
+func (cfg *apiConfig) participantConversation(ctx context.Context, id, userID uuid.UUID) (database.Conversation, error) {
+	c, err := cfg.db.GetConversation(ctx, id)
+	if err != nil {
+		return database.Conversation{}, err
+	}
+	if c.UserAID != userID && c.UserBID != userID {
+		return database.Conversation{}, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+// handlerCreateConversation starts a conversation with another user, or
+// returns the one they already have.
+func (cfg *apiConfig) handlerCreateConversation(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	params := struct {
+		UserID uuid.UUID `json:"user_id"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+	if params.UserID == userID {
+		respondWithError(w, 400, "Cannot message yourself")
+		return
+	}
+	if _, err := cfg.db.GetUser(req.Context(), params.UserID); err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+		UserAID: userID,
+		UserBID: params.UserID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to create conversation")
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusForbidden, "Cannot message this user")
+		return
+	}
+
+	userA, userB := userID, params.UserID
+	if bytes.Compare(userA[:], userB[:]) > 0 {
+		userA, userB = userB, userA
+	}
+	c, err := cfg.db.CreateConversation(req.Context(), database.CreateConversationParams{
+		UserAID: userA,
+		UserBID: userB,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to create conversation")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newConversation(c))
+}
+
+func (cfg *apiConfig) handlerGetConversations(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	conversations, err := cfg.db.ListConversations(req.Context(), database.ListConversationsParams{
+		UserID:          userID,
+		CursorUpdatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get conversations")
+		return
+	}
+	conversations, more := trimPage(conversations, p)
+
+	payload := struct {
+		Conversations []conversation `json:"conversations"`
+		NextCursor    string         `json:"next_cursor,omitempty"`
+	}{
+		Conversations: []conversation{},
+	}
+	for _, c := range conversations {
+		payload.Conversations = append(payload.Conversations, newConversation(c))
+	}
+	if more {
+		last := conversations[len(conversations)-1]
+		payload.NextCursor = encodeCursor(last.UpdatedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
+
+func (cfg *apiConfig) handlerCreateMessage(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	conversationID, err := uuid.Parse(req.PathValue("conversationID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid conversation ID")
+		return
+	}
+	params := struct {
+		Body string `json:"body"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+	body := censorProfanity(params.Body)
+	if body == "" {
+		respondWithError(w, 400, "Message is empty")
+		return
+	}
+	if len(body) > maxMessageLength {
+		respondWithError(w, 400, "Message is too long")
+		return
+	}
+
+	c, err := cfg.participantConversation(req.Context(), conversationID, userID)
+	if err != nil {
+		respondWithError(w, 404, "Conversation not found")
+		return
+	}
+	blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+		UserAID: c.UserAID,
+		UserBID: c.UserBID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to send message")
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusForbidden, "Cannot message this user")
+		return
+	}
+	m, err := cfg.db.CreateMessage(req.Context(), database.CreateMessageParams{
+		ConversationID: conversationID,
+		SenderID:       userID,
+		Body:           body,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to send message")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newMessage(m))
+}
+
+// handlerGetMessages pages backwards through a conversation, newest first.
+func (cfg *apiConfig) handlerGetMessages(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	conversationID, err := uuid.Parse(req.PathValue("conversationID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid conversation ID")
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	if _, err := cfg.participantConversation(req.Context(), conversationID, userID); err != nil {
+		respondWithError(w, 404, "Conversation not found")
+		return
+	}
+	messages, err := cfg.db.ListMessages(req.Context(), database.ListMessagesParams{
+		ConversationID:  conversationID,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get messages")
+		return
+	}
+	messages, more := trimPage(messages, p)
+
+	payload := struct {
+		Messages   []message `json:"messages"`
+		NextCursor string    `json:"next_cursor,omitempty"`
+	}{
+		Messages: []message{},
+	}
+	for _, m := range messages {
+		payload.Messages = append(payload.Messages, newMessage(m))
+	}
+	if more {
+		last := messages[len(messages)-1]
+		payload.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	notificationReply   = "reply"
+	notificationMention = "mention"
+	notificationFollow  = "follow"
+	notificationLike    = "like"
+)
+
+// notify tells recipientID that actorID did something. chirpID is the chirp
+// it concerns, or uuid.Nil for follows. Unread notifications of one kind about
+// one chirp are grouped together by the database, so callers can notify once
+// per event. Nothing is sent between users with a block either way, or about
+// a chirp the recipient can't read. Failing to notify never fails the action
+// that caused it.
+func (cfg *apiConfig) notify(ctx context.Context, recipientID, actorID uuid.UUID, kind string, chirpID uuid.UUID) {
+	if recipientID == actorID {
+		return
+	}
+	blocked, err := cfg.db.BlockExists(ctx, database.BlockExistsParams{
+		UserAID: recipientID,
+		UserBID: actorID,
+	})
+	if err != nil {
+		log.Printf("Failed to notify user %v of %v: %v", recipientID, kind, err)
+		return
+	}
+	if blocked {
+		return
+	}
+	if chirpID != uuid.Nil {
+		visible, err := cfg.db.CanViewChirp(ctx, database.CanViewChirpParams{
+			ID:       chirpID,
+			ViewerID: uuid.NullUUID{UUID: recipientID, Valid: true},
+		})
+		if err != nil {
+			log.Printf("Failed to notify user %v of %v: %v", recipientID, kind, err)
+			return
+		}
+		if !visible {
+			return
+		}
+	}
+	n, err := cfg.db.UpsertNotification(ctx, database.UpsertNotificationParams{
+		UserID:  recipientID,
+		Kind:    kind,
+		ChirpID: uuid.NullUUID{UUID: chirpID, Valid: chirpID != uuid.Nil},
+		ActorID: actorID,
+	})
+	if err != nil {
+		log.Printf("Failed to notify user %v of %v: %v", recipientID, kind, err)
+		return
+	}
+	cfg.notificationStream.Publish(newNotification(n))
+}
+
+type notification struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Kind      string        `json:"kind"`
+	ChirpID   uuid.NullUUID `json:"chirp_id"`
+	// ActorID is the latest user to act, and Count how many times anyone
+	// did since the notification was last read.
+	ActorID uuid.UUID `json:"actor_id"`
+	Count   int32     `json:"count"`
+	Read    bool      `json:"read"`
+	// UserID is only used to route live notifications to their recipient.
+	UserID uuid.UUID `json:"-"`
+}
+
+func newNotification(n database.Notification) notification {
+	return notification{
+		ID:        n.ID,
+		CreatedAt: n.CreatedAt,
+		UpdatedAt: n.UpdatedAt,
+		Kind:      n.Kind,
+		ChirpID:   n.ChirpID,
+		ActorID:   n.ActorID,
+		Count:     n.Count,
+		Read:      n.ReadAt.Valid,
+		UserID:    n.UserID,
+	}
+}
+
+func (cfg *apiConfig) handlerGetNotifications(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	query := req.URL.Query()
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid since date, expected RFC 3339")
+		return
+	}
+	p, err := parsePage(query)
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	notifications, err := cfg.db.ListNotifications(req.Context(), database.ListNotificationsParams{
+		UserID:          userID,
+		UnreadOnly:      query.Get("unread") == "true",
+		Since:           since,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get notifications")
+		return
+	}
+	notifications, more := trimPage(notifications, p)
+	unread, err := cfg.db.CountUnreadNotifications(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 400, "Failed to count notifications")
+		return
+	}
+
+	payload := struct {
+		Notifications []notification `json:"notifications"`
+		UnreadCount   int64          `json:"unread_count"`
+		NextCursor    string         `json:"next_cursor,omitempty"`
+	}{
+		Notifications: []notification{},
+		UnreadCount:   unread,
+	}
+	for _, n := range notifications {
+		payload.Notifications = append(payload.Notifications, newNotification(n))
+	}
+	if more {
+		last := notifications[len(notifications)-1]
+		payload.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
+
+func (cfg *apiConfig) handlerReadNotifications(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	// Without any IDs, every notification is marked as read.
+	params := struct {
+		IDs []uuid.UUID `json:"ids"`
+	}{}
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+			respondWithError(w, 400, "Failed to decode request")
+			return
+		}
+	}
+
+	err = cfg.db.MarkNotificationsRead(req.Context(), database.MarkNotificationsReadParams{
+		UserID: userID,
+		Ids:    params.IDs,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to mark notifications as read")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
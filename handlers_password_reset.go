@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/auth"
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/brendenwelch/chirpy/internal/mailer"
+)
+
+const mailTimeout = 30 * time.Second
+
+// sendMail delivers msg in the background, so how long the mail server takes
+// can't tell a client anything. Failures are only logged.
+func (cfg *apiConfig) sendMail(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := cfg.mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send %q: %v", msg.Subject, err)
+		}
+	}()
+}
+
+// handlerRequestPasswordReset emails a reset token to the address given. It
+// responds the same way whether or not the address belongs to anyone.
+func (cfg *apiConfig) handlerRequestPasswordReset(w http.ResponseWriter, req *http.Request) {
+	params := struct {
+		Email string `json:"email"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+
+	user, err := cfg.db.GetUserByEmail(req.Context(), params.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJSON(w, http.StatusAccepted, struct{}{})
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, "Failed to request password reset")
+		return
+	}
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, 400, "Failed to create reset token")
+		return
+	}
+	err = cfg.db.CreatePasswordResetToken(req.Context(), database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to request password reset")
+		return
+	}
+
+	cfg.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Chirpy account.\n\n"+
+			"Your reset token is:\n\n%s\n\n"+
+			"It works once and expires in an hour. If this wasn't you, you can ignore this email.\n", token),
+	})
+	respondWithJSON(w, http.StatusAccepted, struct{}{})
+}
+
+// handlerConfirmPasswordReset sets a new password with a reset token, and
+// logs every session out.
+func (cfg *apiConfig) handlerConfirmPasswordReset(w http.ResponseWriter, req *http.Request) {
+	params := struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+	if params.Password == "" {
+		respondWithError(w, 400, "Password cannot be empty")
+		return
+	}
+	hashed, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, 400, "Failed to hash password")
+		return
+	}
+
+	err = cfg.resetPassword(req.Context(), auth.HashToken(params.Token), hashed)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 400, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, "Failed to reset password")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
+
+// resetPassword uses up the reset token with tokenHash and sets the new
+// password, revoking the user's other reset tokens and refresh tokens.
+func (cfg *apiConfig) resetPassword(ctx context.Context, tokenHash, hashedPassword string) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := cfg.db.WithTx(tx)
+
+	resetToken, err := q.UsePasswordResetToken(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+	err = q.SetUserPassword(ctx, database.SetUserPasswordParams{
+		ID:             resetToken.UserID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return err
+	}
+	if err := q.DeletePasswordResetTokens(ctx, resetToken.UserID); err != nil {
+		return err
+	}
+	if err := q.RevokeUserRefreshTokens(ctx, resetToken.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 25
+	minPollDuration     = 5 * time.Minute
+	maxPollDuration     = 7 * 24 * time.Hour
+	// Polls running longer than this are a Chirpy Red feature.
+	maxFreePollDuration = 24 * time.Hour
+)
+
+type pollParams struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+type poll struct {
+	ID       uuid.UUID    `json:"id"`
+	ClosesAt time.Time    `json:"closes_at"`
+	Closed   bool         `json:"closed"`
+	Options  []pollOption `json:"options"`
+	// TotalVotes and each option's votes are only shown once the viewer
+	// has voted or the poll has closed.
+	TotalVotes *int64     `json:"total_votes,omitempty"`
+	MyVote     *uuid.UUID `json:"my_vote,omitempty"`
+}
+
+type pollOption struct {
+	ID    uuid.UUID `json:"id"`
+	Text  string    `json:"text"`
+	Votes *int64    `json:"votes,omitempty"`
+}
+
+// validatePoll checks a poll userID wants to attach to a chirp, tidying up
+// its options in place.
+func (cfg *apiConfig) validatePoll(ctx context.Context, userID uuid.UUID, params *pollParams) error {
+	if len(params.Options) < minPollOptions || len(params.Options) > maxPollOptions {
+		return errors.New("polls need 2 to 4 options")
+	}
+	seen := map[string]bool{}
+	for i, option := range params.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > maxPollOptionLength {
+			return errors.New("options must be 1 to 25 characters")
+		}
+		if seen[strings.ToLower(option)] {
+			return errors.New("options must be different")
+		}
+		seen[strings.ToLower(option)] = true
+		params.Options[i] = censorProfanity(option)
+	}
+
+	duration := time.Until(params.ClosesAt)
+	if duration < minPollDuration || duration > maxPollDuration {
+		return errors.New("polls must close between 5 minutes and 7 days from now")
+	}
+	if duration > maxFreePollDuration {
+		user, err := cfg.db.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !user.IsChirpyRed {
+			return errors.New("polls longer than a day need Chirpy Red")
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) createPoll(ctx context.Context, chirpID uuid.UUID, params pollParams) error {
+	p, err := cfg.db.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirpID,
+		ClosesAt: params.ClosesAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.db.CreatePollOptions(ctx, database.CreatePollOptionsParams{
+		PollID: p.ID,
+		Texts:  params.Options,
+	})
+}
+
+// listPolls loads the polls on a batch of chirps, keyed by chirp ID.
+func (cfg *apiConfig) listPolls(ctx context.Context, viewerID uuid.NullUUID, chirpIDs []uuid.UUID) (map[uuid.UUID]*poll, error) {
+	rows, err := cfg.db.ListChirpPollTallies(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	polls := map[uuid.UUID]*poll{}
+	var pollIDs []uuid.UUID
+	totals := map[uuid.UUID]int64{}
+	for _, row := range rows {
+		p, ok := polls[row.ChirpID]
+		if !ok {
+			p = &poll{
+				ID:       row.PollID,
+				ClosesAt: row.ClosesAt,
+				Closed:   !time.Now().UTC().Before(row.ClosesAt),
+			}
+			polls[row.ChirpID] = p
+			pollIDs = append(pollIDs, row.PollID)
+		}
+		votes := row.Votes
+		p.Options = append(p.Options, pollOption{
+			ID:    row.OptionID,
+			Text:  row.Text,
+			Votes: &votes,
+		})
+		totals[row.PollID] += row.Votes
+	}
+	if len(polls) == 0 {
+		return polls, nil
+	}
+
+	myVotes := map[uuid.UUID]uuid.UUID{}
+	if viewerID.Valid {
+		votes, err := cfg.db.ListPollVotesByUser(ctx, database.ListPollVotesByUserParams{
+			UserID:  viewerID.UUID,
+			PollIds: pollIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, vote := range votes {
+			myVotes[vote.PollID] = vote.OptionID
+		}
+	}
+
+	for _, p := range polls {
+		if myVote, ok := myVotes[p.ID]; ok {
+			p.MyVote = &myVote
+		}
+		if p.MyVote != nil || p.Closed {
+			total := totals[p.ID]
+			p.TotalVotes = &total
+			continue
+		}
+		for i := range p.Options {
+			p.Options[i].Votes = nil
+		}
+	}
+	return polls, nil
+}
+
+func (cfg *apiConfig) handlerVote(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+	params := struct {
+		OptionID uuid.UUID `json:"option_id"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(req.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	visible, err := cfg.canView(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		respondWithError(w, 400, "Failed to vote")
+		return
+	}
+	if !visible {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+		UserAID: userID,
+		UserBID: chirp.UserID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to vote")
+		return
+	}
+	if blocked {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	p, err := cfg.db.GetPollByChirpID(req.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "Chirp has no poll")
+		return
+	}
+	if !time.Now().UTC().Before(p.ClosesAt) {
+		respondWithError(w, 400, "Poll has closed")
+		return
+	}
+	options, err := cfg.db.ListPollOptions(req.Context(), p.ID)
+	if err != nil {
+		respondWithError(w, 400, "Failed to vote")
+		return
+	}
+	valid := false
+	for _, option := range options {
+		valid = valid || option.ID == params.OptionID
+	}
+	if !valid {
+		respondWithError(w, 400, "Option is not part of this poll")
+		return
+	}
+
+	created, err := cfg.db.CreatePollVote(req.Context(), database.CreatePollVoteParams{
+		PollID:   p.ID,
+		UserID:   userID,
+		OptionID: params.OptionID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to vote")
+		return
+	}
+	if created == 0 {
+		respondWithError(w, http.StatusConflict, "Already voted")
+		return
+	}
+
+	payload, err := cfg.chirpPayload(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load chirp")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, payload)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/brendenwelch/chirpy/internal/chirptext"
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+)
+
+// profile is the public view of a user. It never includes their email.
+type profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+func newProfile(user database.User) profile {
+	return profile{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarUrl,
+		IsChirpyRed: user.IsChirpyRed,
+	}
+}
+
+// author is the compact profile embedded in every chirp.
+type author struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
+}
+
+// listAuthors loads the authors of a batch of chirps with one query.
+func (cfg *apiConfig) listAuthors(ctx context.Context, chirps []database.Chirp) (map[uuid.UUID]*author, error) {
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.UserID
+	}
+	rows, err := cfg.db.ListAuthors(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	authors := make(map[uuid.UUID]*author, len(rows))
+	for _, row := range rows {
+		authors[row.ID] = &author{
+			ID:          row.ID,
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			AvatarURL:   row.AvatarUrl,
+		}
+	}
+	return authors, nil
+}
+
+// validText reports whether s is at most maxLength characters of printable
+// text. Line breaks are allowed only if multiline is set.
+func validText(s string, maxLength int, multiline bool) bool {
+	if !utf8.ValidString(s) || utf8.RuneCountInString(s) > maxLength {
+		return false
+	}
+	for _, r := range s {
+		if r == '\n' && multiline {
+			continue
+		}
+		if !unicode.IsPrint(r) && r != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// validAvatarURL accepts an absolute http(s) URL, or an empty string to
+// remove the avatar.
+func validAvatarURL(s string) bool {
+	if s == "" {
+		return true
+	}
+	if len(s) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// handlerUpdateProfile changes any of the fields given, leaving the others as
+// they are.
+func (cfg *apiConfig) handlerUpdateProfile(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	params := struct {
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+
+	user, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	update := database.UpdateProfileParams{
+		ID:          userID,
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarUrl:   user.AvatarUrl,
+	}
+	if params.Handle != nil {
+		update.Handle = strings.ToLower(strings.TrimPrefix(*params.Handle, "@"))
+		if !chirptext.ValidHandle(update.Handle) {
+			respondWithError(w, 400, "Handles must be 3-15 letters, digits or underscores")
+			return
+		}
+	}
+	if params.DisplayName != nil {
+		update.DisplayName = strings.TrimSpace(*params.DisplayName)
+		if !validText(update.DisplayName, maxDisplayNameLength, false) {
+			respondWithError(w, 400, "Display names must be at most 50 characters on one line")
+			return
+		}
+	}
+	if params.Bio != nil {
+		update.Bio = strings.TrimSpace(*params.Bio)
+		if !validText(update.Bio, maxBioLength, true) {
+			respondWithError(w, 400, "Bios must be at most 160 characters")
+			return
+		}
+	}
+	if params.AvatarURL != nil {
+		update.AvatarUrl = strings.TrimSpace(*params.AvatarURL)
+		if !validAvatarURL(update.AvatarUrl) {
+			respondWithError(w, 400, "Avatar URL must be an http or https URL")
+			return
+		}
+	}
+
+	user, err = cfg.db.UpdateProfile(req.Context(), update)
+	if err != nil {
+		respondWithError(w, 400, "Failed to update profile. Is the handle already taken?")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newProfile(user))
+}
+
+func (cfg *apiConfig) handlerGetProfile(w http.ResponseWriter, req *http.Request) {
+	handle := strings.ToLower(strings.TrimPrefix(req.PathValue("handle"), "@"))
+	user, err := cfg.db.GetUserByHandle(req.Context(), handle)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	if viewerID := cfg.viewer(req); viewerID.Valid {
+		blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+			UserAID: viewerID.UUID,
+			UserBID: user.ID,
+		})
+		if err != nil {
+			respondWithError(w, 400, "Failed to get user")
+			return
+		}
+		if blocked {
+			respondWithError(w, 404, "User not found")
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, newProfile(user))
+}
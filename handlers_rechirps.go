@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// resolveOriginal finds the chirp that a rechirp or quote of id should point
+// at. Rechirping a rechirp shares the chirp it shared, not the empty rechirp.
+// Chirps viewerID can't read are treated as missing.
+func (cfg *apiConfig) resolveOriginal(ctx context.Context, viewerID uuid.NullUUID, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := cfg.db.GetChirp(ctx, id)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if chirp.IsRechirp && chirp.OriginalID.Valid {
+		chirp, err = cfg.db.GetChirp(ctx, chirp.OriginalID.UUID)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	if chirp.DeletedAt.Valid {
+		return database.Chirp{}, errors.New("chirp has been deleted")
+	}
+	visible, err := cfg.canView(ctx, viewerID, chirp)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if !visible {
+		return database.Chirp{}, errors.New("chirp is not visible")
+	}
+	return chirp, nil
+}
+
+func (cfg *apiConfig) handlerRechirp(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	if err := cfg.checkEmailVerified(req.Context(), userID); err != nil {
+		respondWithEmailVerifiedError(w, err)
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+	original, err := cfg.resolveOriginal(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirpID)
+	if err != nil {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	// Rechirps are shown to everyone, so sharing anything else would leak it.
+	if original.Visibility != visibilityPublic {
+		respondWithError(w, 400, "Only public chirps can be rechirped")
+		return
+	}
+	blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+		UserAID: userID,
+		UserBID: original.UserID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to rechirp")
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusForbidden, "Cannot rechirp this user")
+		return
+	}
+
+	chirp, err := cfg.db.CreateRechirp(req.Context(), database.CreateRechirpParams{
+		UserID:     userID,
+		OriginalID: original.ID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to rechirp. Has it already been rechirped?")
+		return
+	}
+	cfg.publishChirp(req.Context(), chirp)
+
+	payload, err := cfg.chirpPayload(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load chirp")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, payload)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerEditChirp(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+
+	params := struct {
+		Body string `json:"body"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(req.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	if userID != chirp.UserID {
+		respondWithError(w, 403, "Not authorized to edit this chirp")
+		return
+	}
+	if chirp.IsRechirp {
+		respondWithError(w, 400, "Rechirps cannot be edited")
+		return
+	}
+	user, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 400, "Failed to get user")
+		return
+	}
+	if !user.IsChirpyRed {
+		respondWithError(w, 403, "Editing chirps requires Chirpy Red")
+		return
+	}
+
+	cleaned, err := cleanChirpBody(params.Body)
+	if err != nil {
+		respondWithError(w, 400, "Chirp is too long")
+		return
+	}
+	if cleaned == "" {
+		respondWithError(w, 400, "Chirp body cannot be empty")
+		return
+	}
+
+	// Only users the edit mentions for the first time are notified.
+	previous, err := cfg.db.ListChirpMentions(req.Context(), []uuid.UUID{chirp.ID})
+	if err != nil {
+		respondWithError(w, 400, "Failed to edit chirp")
+		return
+	}
+	alreadyMentioned := make(map[uuid.UUID]bool, len(previous))
+	for _, m := range previous {
+		alreadyMentioned[m.UserID] = true
+	}
+
+	chirp, err = cfg.db.EditChirp(req.Context(), database.EditChirpParams{
+		ID:   chirp.ID,
+		Body: cleaned,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to edit chirp")
+		return
+	}
+	mentioned, err := indexChirp(req.Context(), cfg.db, chirp)
+	if err != nil {
+		log.Printf("Failed to index chirp %v: %v", chirp.ID, err)
+	}
+	for _, mentionedID := range mentioned {
+		if !alreadyMentioned[mentionedID] {
+			cfg.notify(req.Context(), mentionedID, userID, notificationMention, chirp.ID)
+		}
+	}
+
+	payload, err := cfg.chirpPayload(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load chirp")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
+
+func (cfg *apiConfig) handlerGetChirpRevisions(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+	chirp, err := cfg.db.GetChirp(req.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	viewerID := cfg.viewer(req)
+	if viewerID.Valid {
+		blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+			UserAID: viewerID.UUID,
+			UserBID: chirp.UserID,
+		})
+		if err != nil {
+			respondWithError(w, 400, "Failed to get revisions")
+			return
+		}
+		if blocked {
+			respondWithError(w, 404, "Chirp not found")
+			return
+		}
+	}
+	visible, err := cfg.canView(req.Context(), viewerID, chirp)
+	if err != nil {
+		respondWithError(w, 400, "Failed to get revisions")
+		return
+	}
+	if !visible {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+
+	revisions, err := cfg.db.ListChirpRevisions(req.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, 400, "Failed to get revisions")
+		return
+	}
+
+	type Revision struct {
+		ID         uuid.UUID `json:"id"`
+		Body       string    `json:"body"`
+		CreatedAt  time.Time `json:"created_at"`
+		ReplacedAt time.Time `json:"replaced_at"`
+	}
+	payload := struct {
+		ChirpID   uuid.UUID  `json:"chirp_id"`
+		Revisions []Revision `json:"revisions"`
+	}{
+		ChirpID:   chirp.ID,
+		Revisions: []Revision{},
+	}
+	for _, revision := range revisions {
+		payload.Revisions = append(payload.Revisions, Revision{
+			ID:         revision.ID,
+			Body:       revision.Body,
+			CreatedAt:  revision.CreatedAt,
+			ReplacedAt: revision.ReplacedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	schedulerInterval = 10 * time.Second
+	maxScheduleAhead  = 365 * 24 * time.Hour
+)
+
+type scheduledChirp struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Body       string    `json:"body"`
+	Visibility string    `json:"visibility"`
+	PublishAt  time.Time `json:"publish_at"`
+}
+
+func newScheduledChirp(s database.ScheduledChirp) scheduledChirp {
+	return scheduledChirp{
+		ID:         s.ID,
+		CreatedAt:  s.CreatedAt,
+		Body:       s.Body,
+		Visibility: s.Visibility,
+		PublishAt:  s.PublishAt,
+	}
+}
+
+// scheduleChirp handles POST /api/chirps with a publish_at. The body has
+// already been cleaned.
+func (cfg *apiConfig) scheduleChirp(w http.ResponseWriter, req *http.Request, userID uuid.UUID, body, visibility string, publishAt time.Time) {
+	wait := time.Until(publishAt)
+	if wait <= 0 || wait > maxScheduleAhead {
+		respondWithError(w, 400, "publish_at must be in the next year")
+		return
+	}
+
+	scheduled, err := cfg.db.CreateScheduledChirp(req.Context(), database.CreateScheduledChirpParams{
+		UserID:     userID,
+		Body:       body,
+		PublishAt:  publishAt.UTC(),
+		Visibility: visibility,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to schedule chirp")
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, newScheduledChirp(scheduled))
+}
+
+// handlerGetScheduledChirps lists the caller's chirps still waiting to go out,
+// soonest first.
+func (cfg *apiConfig) handlerGetScheduledChirps(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	scheduled, err := cfg.db.ListScheduledChirps(req.Context(), database.ListScheduledChirpsParams{
+		UserID:          userID,
+		CursorPublishAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get scheduled chirps")
+		return
+	}
+	scheduled, more := trimPage(scheduled, p)
+
+	payload := struct {
+		Chirps     []scheduledChirp `json:"chirps"`
+		NextCursor string           `json:"next_cursor,omitempty"`
+	}{
+		Chirps: []scheduledChirp{},
+	}
+	for _, s := range scheduled {
+		payload.Chirps = append(payload.Chirps, newScheduledChirp(s))
+	}
+	if more {
+		last := scheduled[len(scheduled)-1]
+		payload.NextCursor = encodeCursor(last.PublishAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
+
+func (cfg *apiConfig) handlerCancelScheduledChirp(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	id, err := uuid.Parse(req.PathValue("scheduledID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid scheduled chirp ID")
+		return
+	}
+
+	// If the scheduler is publishing this chirp right now, the delete waits
+	// for it and then finds nothing left to cancel.
+	canceled, err := cfg.db.CancelScheduledChirp(req.Context(), database.CancelScheduledChirpParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to cancel scheduled chirp")
+		return
+	}
+	if canceled == 0 {
+		respondWithError(w, 404, "Scheduled chirp not found or already published")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
+
+// runScheduler publishes due chirps every schedulerInterval until ctx is done.
+// Everything it needs is in the database, so chirps that fell due while the
+// server was down go out as soon as it starts again.
+func (cfg *apiConfig) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		cfg.publishDueChirps(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) {
+	for ctx.Err() == nil {
+		chirp, mentioned, err := cfg.publishNextScheduledChirp(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to publish scheduled chirp: %v", err)
+			return
+		}
+
+		cfg.publishChirp(ctx, chirp)
+		for _, mentionedID := range mentioned {
+			cfg.notify(ctx, mentionedID, chirp.UserID, notificationMention, chirp.ID)
+		}
+	}
+}
+
+// publishNextScheduledChirp turns the next due scheduled chirp into a chirp,
+// and returns it with the IDs of the users it mentions. Claiming it,
+// creating and indexing the chirp and marking it published happen in one
+// transaction, and the claim skips rows another scheduler holds, so each one
+// is published exactly once even with several servers running.
+func (cfg *apiConfig) publishNextScheduledChirp(ctx context.Context) (database.Chirp, []uuid.UUID, error) {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+	defer tx.Rollback()
+	q := cfg.db.WithTx(tx)
+
+	scheduled, err := q.ClaimDueScheduledChirp(ctx)
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+	chirp, err := q.CreateChirp(ctx, database.CreateChirpParams{
+		Body:       scheduled.Body,
+		UserID:     scheduled.UserID,
+		Visibility: scheduled.Visibility,
+	})
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+	err = q.MarkScheduledChirpPublished(ctx, database.MarkScheduledChirpPublishedParams{
+		ID:      scheduled.ID,
+		ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+	})
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+	mentioned, err := indexChirp(ctx, q, chirp)
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+	return chirp, mentioned, tx.Commit()
+}
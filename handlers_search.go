@@ -0,0 +1,93 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/brendenwelch/chirpy/internal/search"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerSearchChirps(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	tsQuery, err := search.ToTSQuery(query.Get("q"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid search query: "+err.Error())
+		return
+	}
+
+	var authorID uuid.NullUUID
+	if authorIDString := query.Get("author_id"); authorIDString != "" {
+		id, err := uuid.Parse(authorIDString)
+		if err != nil {
+			respondWithError(w, 400, "Invalid chirp author id")
+			return
+		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid since date, expected RFC 3339")
+		return
+	}
+	until, err := parseTimeParam(query.Get("until"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid until date, expected RFC 3339")
+		return
+	}
+	p, err := parseRankedPage(query)
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	viewerID := cfg.viewer(req)
+	results, err := cfg.db.SearchChirps(req.Context(), database.SearchChirpsParams{
+		Query:           tsQuery,
+		AuthorID:        authorID,
+		ViewerID:        viewerID,
+		Since:           since,
+		Until:           until,
+		CursorRank:      p.cursorRank,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to search chirps")
+		return
+	}
+	results, more := trimPage(results, p.page)
+
+	chirps := make([]database.Chirp, len(results))
+	for i, result := range results {
+		chirps[i] = result.Chirp
+	}
+	payloads, err := cfg.chirpPayloads(req.Context(), viewerID, chirps)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load chirps")
+		return
+	}
+	payload := chirpPage{
+		Chirps: payloads,
+	}
+	if more {
+		last := results[len(results)-1]
+		payload.NextCursor = encodeRankedCursor(last.Rank, last.Chirp.CreatedAt, last.Chirp.ID)
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
+
+// parseTimeParam parses an optional RFC 3339 query parameter.
+func parseTimeParam(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
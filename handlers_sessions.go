@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brendenwelch/chirpy/internal/auth"
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxUserAgentLength = 512
+
+// session is one login, identified by its refresh token family. It lasts
+// until its latest refresh token expires or is revoked.
+type session struct {
+	ID         uuid.UUID `json:"id"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+}
+
+// clientInfo describes the device making req, for recording on its session.
+func clientInfo(req *http.Request) (userAgent, ip string) {
+	// Postgres only stores valid UTF-8, so never cut a character in half.
+	userAgent = strings.ToValidUTF8(req.UserAgent(), "")
+	if len(userAgent) > maxUserAgentLength {
+		end := maxUserAgentLength
+		for end > 0 && !utf8.RuneStart(userAgent[end]) {
+			end--
+		}
+		userAgent = userAgent[:end]
+	}
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+	return userAgent, ip
+}
+
+func (cfg *apiConfig) handlerGetSessions(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+
+	rows, err := cfg.db.ListSessions(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 400, "Failed to get sessions")
+		return
+	}
+	payload := struct {
+		Sessions []session `json:"sessions"`
+	}{
+		Sessions: []session{},
+	}
+	for _, row := range rows {
+		payload.Sessions = append(payload.Sessions, session{
+			ID:         row.FamilyID,
+			SignedInAt: row.SignedInAt,
+			LastUsedAt: row.LastUsedAt,
+			ExpiresAt:  row.ExpiresAt,
+			UserAgent:  row.UserAgent,
+			IP:         row.Ip,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
+
+// handlerRevokeSession logs a session out. Access tokens already issued to it
+// stay valid until they expire.
+func (cfg *apiConfig) handlerRevokeSession(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	sessionID, err := uuid.Parse(req.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid session ID")
+		return
+	}
+
+	revoked, err := cfg.db.RevokeSession(req.Context(), database.RevokeSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to revoke session")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, 404, "Session not found")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
+
+// handlerRevokeOtherSessions logs out every session but the one whose refresh
+// token is on the request, like POST /api/revoke.
+func (cfg *apiConfig) handlerRevokeOtherSessions(w http.ResponseWriter, req *http.Request) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		respondWithError(w, 400, "No valid token provided")
+		return
+	}
+	refreshToken, err := cfg.db.GetRefreshToken(req.Context(), token)
+	if err != nil {
+		respondWithError(w, 401, "Refresh token not in database")
+		return
+	}
+	err = auth.CheckRefreshToken(time.Now().UTC(), refreshToken.ExpiresAt, refreshToken.RevokedAt, refreshToken.RotatedAt)
+	if errors.Is(err, auth.ErrRefreshTokenReused) {
+		cfg.revokeRefreshTokenFamily(req.Context(), refreshToken)
+	}
+	if err != nil {
+		respondWithError(w, 401, "Invalid refresh token: "+err.Error())
+		return
+	}
+
+	err = cfg.db.RevokeOtherSessions(req.Context(), database.RevokeOtherSessionsParams{
+		UserID:   refreshToken.UserID,
+		FamilyID: refreshToken.FamilyID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to revoke sessions")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
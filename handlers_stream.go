@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/brendenwelch/chirpy/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+const (
+	streamBuffer    = 64
+	streamHeartbeat = 15 * time.Second
+	// streamRefresh is how often a stream reloads the relationships it
+	// filters on, to pick up changes made through another server.
+	streamRefresh = 30 * time.Second
+)
+
+// streamViewer holds the relationships a live stream filters on for one
+// signed-in user. They are reloaded every streamRefresh, and straight away
+// when the user's own follows, blocks or mutes change, so a long-lived stream
+// doesn't keep showing chirps the user can no longer read, or want to.
+type streamViewer struct {
+	cfg    *apiConfig
+	userID uuid.UUID
+
+	mu        sync.RWMutex
+	following map[uuid.UUID]bool
+	hidden    map[uuid.UUID]bool
+}
+
+// newStreamViewer loads userID's relationships and keeps them fresh until ctx
+// is done.
+func (cfg *apiConfig) newStreamViewer(ctx context.Context, userID uuid.UUID) (*streamViewer, error) {
+	v := &streamViewer{cfg: cfg, userID: userID}
+	// Subscribe before loading, so a change made in between isn't missed.
+	sub, err := v.subscribe()
+	if err != nil {
+		return nil, err
+	}
+	if err := v.reload(ctx); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	go v.run(ctx, sub)
+	return v, nil
+}
+
+func (v *streamViewer) subscribe() (*pubsub.Subscription[uuid.UUID], error) {
+	return v.cfg.relationshipStream.Subscribe(streamBuffer, func(id uuid.UUID) bool {
+		return id == v.userID
+	})
+}
+
+func (v *streamViewer) run(ctx context.Context, sub *pubsub.Subscription[uuid.UUID]) {
+	ticker := time.NewTicker(streamRefresh)
+	defer ticker.Stop()
+	defer func() {
+		if sub != nil {
+			sub.Close()
+		}
+	}()
+	var changes <-chan uuid.UUID = sub.C
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case _, ok := <-changes:
+			if !ok {
+				// Dropped for falling behind, or the server is shutting
+				// down. Try to resubscribe; the ticker still catches up
+				// if that fails.
+				sub, changes = nil, nil
+				if s, err := v.subscribe(); err == nil {
+					sub, changes = s, s.C
+				}
+			}
+		}
+		if err := v.reload(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to reload relationships of user %v: %v", v.userID, err)
+		}
+	}
+}
+
+func (v *streamViewer) reload(ctx context.Context) error {
+	following, err := v.cfg.followingSet(ctx, v.userID)
+	if err != nil {
+		return err
+	}
+	hidden, err := v.cfg.hiddenAuthorSet(ctx, v.userID)
+	if err != nil {
+		return err
+	}
+	v.mu.Lock()
+	v.following = following
+	v.hidden = hidden
+	v.mu.Unlock()
+	return nil
+}
+
+// shows reports whether chirp should be sent to the viewer. A nil viewer is
+// someone who isn't signed in, and only sees public chirps.
+func (v *streamViewer) shows(chirp Chirp) bool {
+	if v == nil {
+		return chirpVisible(chirp, uuid.NullUUID{}, nil)
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if chirp.Original != nil && v.hidden[chirp.Original.UserID] {
+		return false
+	}
+	viewerID := uuid.NullUUID{UUID: v.userID, Valid: true}
+	return !v.hidden[chirp.UserID] && chirpVisible(chirp, viewerID, v.following)
+}
+
+// follows reports whether the viewer follows userID.
+func (v *streamViewer) follows(userID uuid.UUID) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.following[userID]
+}
+
+// publishChirp pushes a newly stored chirp to everyone streaming chirps. The
+// payload is built once, without a viewer, and shared by every subscriber.
+func (cfg *apiConfig) publishChirp(ctx context.Context, chirp database.Chirp) {
+	payload, err := cfg.chirpPayload(ctx, uuid.NullUUID{}, chirp)
+	if err != nil {
+		log.Printf("Failed to publish chirp %v: %v", chirp.ID, err)
+		return
+	}
+	cfg.chirpStream.Publish(payload)
+}
+
+// handlerStreamChirps sends new chirps as Server-Sent Events. Each event's ID
+// is a pagination cursor, so a client reconnecting with Last-Event-ID first
+// gets every chirp it missed and then carries on with live ones. Signed-in
+// clients also get the non-public chirps they can read, and don't get chirps
+// from accounts they have blocked, been blocked by or muted.
+func (cfg *apiConfig) handlerStreamChirps(w http.ResponseWriter, req *http.Request) {
+	var authorID uuid.NullUUID
+	if authorIDString := req.URL.Query().Get("author_id"); authorIDString != "" {
+		id, err := uuid.Parse(authorIDString)
+		if err != nil {
+			respondWithError(w, 400, "Invalid chirp author id")
+			return
+		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	var p page
+	if lastEventID := req.Header.Get("Last-Event-ID"); lastEventID != "" {
+		createdAt, id, err := decodeCursor(lastEventID)
+		if err != nil {
+			respondWithError(w, 400, "Invalid Last-Event-ID")
+			return
+		}
+		p.cursorCreatedAt.Time, p.cursorCreatedAt.Valid = createdAt, true
+		p.cursorID.UUID, p.cursorID.Valid = id, true
+	}
+	viewerID := cfg.viewer(req)
+	var viewer *streamViewer
+	if viewerID.Valid {
+		var err error
+		viewer, err = cfg.newStreamViewer(req.Context(), viewerID.UUID)
+		if err != nil {
+			respondWithError(w, 400, "Failed to get followed, blocked and muted users")
+			return
+		}
+	}
+
+	// Subscribe before replaying, so nothing published in between is lost.
+	sub, err := cfg.chirpStream.Subscribe(streamBuffer, func(chirp Chirp) bool {
+		return (!authorID.Valid || chirp.UserID == authorID.UUID) && viewer.shows(chirp)
+	})
+	if err != nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Server is shutting down")
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		log.Printf("Streaming not supported: %v", err)
+		return
+	}
+
+	var last Chirp
+	if p.cursorCreatedAt.Valid {
+		for {
+			chirps, err := cfg.db.ListChirpsAsc(req.Context(), database.ListChirpsAscParams{
+				AuthorID:        authorID,
+				ViewerID:        viewerID,
+				CursorCreatedAt: p.cursorCreatedAt,
+				CursorID:        p.cursorID,
+				Limit:           maxPageLimit,
+			})
+			if err != nil {
+				log.Printf("Failed to replay chirps: %v", err)
+				return
+			}
+			payloads, err := cfg.chirpPayloads(req.Context(), uuid.NullUUID{}, chirps)
+			if err != nil {
+				log.Printf("Failed to replay chirps: %v", err)
+				return
+			}
+			for _, chirp := range payloads {
+				last = chirp
+				if !viewer.shows(chirp) {
+					continue
+				}
+				if err := writeChirpEvent(w, chirp); err != nil {
+					return
+				}
+			}
+			if len(chirps) < maxPageLimit {
+				break
+			}
+			p.cursorCreatedAt.Time = last.CreatedAt
+			p.cursorID.UUID = last.ID
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case chirp, ok := <-sub.C:
+			if !ok {
+				// Either the server is shutting down or we fell too far
+				// behind. The client will reconnect with Last-Event-ID.
+				return
+			}
+			if !last.CreatedAt.IsZero() && !chirpAfter(chirp, last) {
+				continue
+			}
+			if err := writeChirpEvent(w, chirp); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// chirpAfter reports whether a comes after b in (created_at, id) order.
+func chirpAfter(a, b Chirp) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID.String() > b.ID.String()
+}
+
+func writeChirpEvent(w http.ResponseWriter, chirp Chirp) error {
+	data, err := json.Marshal(chirp)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: chirp\ndata: %s\n\n", encodeCursor(chirp.CreatedAt, chirp.ID), data)
+	return err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/chirptext"
+	"github.com/brendenwelch/chirpy/internal/database"
+)
+
+const (
+	trendingWindow   = 24 * time.Hour
+	trendingInterval = time.Minute
+	trendingLimit    = 10
+)
+
+type trendingTag struct {
+	Name string `json:"name"`
+	Uses int64  `json:"uses"`
+}
+
+// trendingTags caches the most used tags of the last trendingWindow. It is
+// refreshed in the background by runTrendingTags rather than per request.
+type trendingTags struct {
+	mu         sync.RWMutex
+	tags       []trendingTag
+	computedAt time.Time
+}
+
+// tagChirp makes the tags stored for chirp match the hashtags in its body.
+// Tags it already had are left alone, and every tag is dated from when the
+// chirp was posted, so editing an old chirp doesn't make its tags trend.
+func tagChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	names := chirptext.Hashtags(chirp.Body)
+	err := q.DeleteOtherChirpTags(ctx, database.DeleteOtherChirpTagsParams{
+		ChirpID: chirp.ID,
+		Names:   names,
+	})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if err := q.CreateTags(ctx, names); err != nil {
+		return err
+	}
+	return q.CreateChirpTags(ctx, database.CreateChirpTagsParams{
+		ChirpID: chirp.ID,
+		Names:   names,
+	})
+}
+
+// runTrendingTags recomputes the trending tags every trendingInterval until
+// ctx is done.
+func (cfg *apiConfig) runTrendingTags(ctx context.Context) {
+	ticker := time.NewTicker(trendingInterval)
+	defer ticker.Stop()
+	for {
+		cfg.refreshTrendingTags(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) refreshTrendingTags(ctx context.Context) {
+	rows, err := cfg.db.ListTrendingTags(ctx, database.ListTrendingTagsParams{
+		WindowSeconds: int32(trendingWindow.Seconds()),
+		Limit:         trendingLimit,
+	})
+	if err != nil {
+		log.Printf("Failed to refresh trending tags: %v", err)
+		return
+	}
+	tags := make([]trendingTag, len(rows))
+	for i, row := range rows {
+		tags[i] = trendingTag{Name: row.Name, Uses: row.Uses}
+	}
+
+	cfg.trending.mu.Lock()
+	defer cfg.trending.mu.Unlock()
+	cfg.trending.tags = tags
+	cfg.trending.computedAt = time.Now().UTC()
+}
+
+func (cfg *apiConfig) handlerTrendingTags(w http.ResponseWriter, req *http.Request) {
+	cfg.trending.mu.RLock()
+	defer cfg.trending.mu.RUnlock()
+
+	tags := cfg.trending.tags
+	if tags == nil {
+		tags = []trendingTag{}
+	}
+	respondWithJSON(w, http.StatusOK, struct {
+		Tags       []trendingTag `json:"tags"`
+		Window     string        `json:"window"`
+		ComputedAt time.Time     `json:"computed_at"`
+	}{
+		Tags:       tags,
+		Window:     trendingWindow.String(),
+		ComputedAt: cfg.trending.computedAt,
+	})
+}
+
+func (cfg *apiConfig) handlerGetTagChirps(w http.ResponseWriter, req *http.Request) {
+	name := strings.ToLower(strings.TrimPrefix(req.PathValue("tag"), "#"))
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, "Invalid pagination: "+err.Error())
+		return
+	}
+
+	viewerID := cfg.viewer(req)
+	chirps, err := cfg.db.ListChirpsByTag(req.Context(), database.ListChirpsByTagParams{
+		Name:            name,
+		ViewerID:        viewerID,
+		CursorCreatedAt: p.cursorCreatedAt,
+		CursorID:        p.cursorID,
+		Limit:           p.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get chirps")
+		return
+	}
+	chirps, more := trimPage(chirps, p)
+
+	payload, err := cfg.newChirpPage(req.Context(), viewerID, chirps, more)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, payload)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// threadNode is one chirp in a conversation tree. Chirps deleted while they
+// still have replies are kept as tombstones: they hold their place in the
+// tree, but their body is gone and Deleted is set.
+type threadNode struct {
+	Chirp
+	Replies []*threadNode `json:"replies"`
+}
+
+func (cfg *apiConfig) handlerGetThread(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID")
+		return
+	}
+	chirp, err := cfg.db.GetChirp(req.Context(), id)
+	if err != nil {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	viewerID := cfg.viewer(req)
+	if viewerID.Valid {
+		blocked, err := cfg.db.BlockExists(req.Context(), database.BlockExistsParams{
+			UserAID: viewerID.UUID,
+			UserBID: chirp.UserID,
+		})
+		if err != nil {
+			respondWithError(w, 400, "Failed to get thread")
+			return
+		}
+		if blocked {
+			respondWithError(w, 404, "Chirp not found")
+			return
+		}
+	}
+	visible, err := cfg.canView(req.Context(), viewerID, chirp)
+	if err != nil {
+		respondWithError(w, 400, "Failed to get thread")
+		return
+	}
+	if !visible {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	rootID := chirp.ID
+	if chirp.ThreadID.Valid {
+		rootID = chirp.ThreadID.UUID
+	}
+
+	// Replies the viewer can't read, and those by anyone they blocked, muted
+	// or were blocked by, are left out. If that includes the root, there is
+	// no thread to show them.
+	chirps, err := cfg.db.GetThread(req.Context(), database.GetThreadParams{
+		ID:       rootID,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "Failed to get thread")
+		return
+	}
+	payloads, err := cfg.chirpPayloads(req.Context(), viewerID, chirps)
+	if err != nil {
+		respondWithError(w, 400, "Failed to load thread")
+		return
+	}
+	root := buildThread(rootID, chirps, payloads)
+	if root == nil {
+		respondWithError(w, 404, "Thread not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, root)
+}
+
+// buildThread arranges chirps, which must be ordered oldest first, into a tree
+// under the chirp with rootID. payloads holds the JSON form of each chirp.
+// Replies whose parent is missing are attached to the root so they are never
+// lost from the conversation.
+func buildThread(rootID uuid.UUID, chirps []database.Chirp, payloads []Chirp) *threadNode {
+	nodes := make(map[uuid.UUID]*threadNode, len(chirps))
+	for i, chirp := range chirps {
+		nodes[chirp.ID] = &threadNode{
+			Chirp:   payloads[i],
+			Replies: []*threadNode{},
+		}
+	}
+	root, ok := nodes[rootID]
+	if !ok {
+		return nil
+	}
+	for _, chirp := range chirps {
+		if chirp.ID == rootID {
+			continue
+		}
+		parent, ok := nodes[chirp.ParentID.UUID]
+		if !chirp.ParentID.Valid || !ok {
+			parent = root
+		}
+		parent.Replies = append(parent.Replies, nodes[chirp.ID])
+	}
+	return root
+}
+
+// deleteChirp removes a chirp. A chirp that is still replied to, rechirped or
+// quoted becomes a tombstone so those references keep pointing somewhere;
+// otherwise it is deleted outright, along with any tombstones it was the last
+// reference to. Either way its media is deleted too.
+func (cfg *apiConfig) deleteChirp(ctx context.Context, chirp database.Chirp) error {
+	references, err := cfg.db.CountChirpReferences(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	files, err := cfg.db.DeleteChirpMediaFiles(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		cfg.deleteMediaFiles(ctx, file.StorageKey, file.ThumbnailKey)
+	}
+	if references > 0 {
+		return cfg.db.TombstoneChirp(ctx, chirp.ID)
+	}
+	if err := cfg.db.DeleteChirp(ctx, chirp.ID); err != nil {
+		return err
+	}
+
+	for _, id := range []uuid.NullUUID{chirp.ParentID, chirp.OriginalID} {
+		if !id.Valid {
+			continue
+		}
+		referenced, err := cfg.db.GetChirp(ctx, id.UUID)
+		if err != nil || !referenced.DeletedAt.Valid {
+			continue
+		}
+		if err := cfg.deleteChirp(ctx, referenced); err != nil {
+			return err
+		}
+	}
+	return nil
+}
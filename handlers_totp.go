@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/auth"
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer       = "Chirpy"
+	mfaTokenDuration = 5 * time.Minute
+	// After maxMFAAttempts wrong codes in a row, a user's second factor is
+	// locked for mfaLockout from the last one.
+	maxMFAAttempts = 5
+	mfaLockout     = 15 * time.Minute
+)
+
+var (
+	errMFALocked      = errors.New("too many failed attempts, try again later")
+	errInvalidMFACode = errors.New("invalid code")
+)
+
+// mfaEnabled reports whether userID has confirmed a TOTP secret.
+func (cfg *apiConfig) mfaEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	totp, err := cfg.db.GetTOTPSecret(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totp.ConfirmedAt.Valid, nil
+}
+
+// respondWithMFAChallenge answers the first step of a login with two-factor
+// authentication. The client exchanges the challenge token and a code for
+// the usual tokens at POST /api/login/mfa.
+func (cfg *apiConfig) respondWithMFAChallenge(w http.ResponseWriter, userID uuid.UUID) {
+	mfaToken, err := auth.MakeMFAToken(userID, cfg.secret, time.Now(), mfaTokenDuration)
+	if err != nil {
+		respondWithError(w, 400, "Failed to create MFA token")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+	})
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code
+// for a confirmed secret. Each one only works once. Every attempt counts as a
+// failure until it succeeds, and is counted before the code is checked, so
+// guesses sent in parallel can't get past the limit.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, totp database.TotpSecret, code, recoveryCode string) error {
+	_, err := cfg.db.ClaimTOTPAttempt(ctx, database.ClaimTOTPAttemptParams{
+		WindowSeconds: int32(mfaLockout.Seconds()),
+		UserID:        totp.UserID,
+		MaxAttempts:   maxMFAAttempts,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return errMFALocked
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case recoveryCode != "":
+		used, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   totp.UserID,
+			CodeHash: auth.HashRecoveryCode(recoveryCode),
+		})
+		if err != nil {
+			return err
+		}
+		if used > 0 {
+			return cfg.db.ResetTOTPFailures(ctx, totp.UserID)
+		}
+	case code != "":
+		step, err := auth.ValidateTOTP(totp.Secret, code, time.Now())
+		if err != nil && !errors.Is(err, auth.ErrInvalidTOTPCode) {
+			return err
+		}
+		if err == nil {
+			// This also resets the failed attempts.
+			used, err := cfg.db.UseTOTPStep(ctx, database.UseTOTPStepParams{
+				Step:   step,
+				UserID: totp.UserID,
+			})
+			if err != nil {
+				return err
+			}
+			if used > 0 {
+				return nil
+			}
+		}
+	}
+	return errInvalidMFACode
+}
+
+// respondWithSecondFactorError reports a failed checkSecondFactor.
+func respondWithSecondFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errMFALocked):
+		respondWithError(w, http.StatusTooManyRequests, "Too many failed attempts, try again later")
+	case errors.Is(err, errInvalidMFACode):
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+	default:
+		respondWithError(w, 400, "Failed to check code")
+	}
+}
+
+// handlerLoginMFA is the second step of a login with two-factor
+// authentication.
+func (cfg *apiConfig) handlerLoginMFA(w http.ResponseWriter, req *http.Request) {
+	params := struct {
+		MFAToken         string `json:"mfa_token"`
+		Code             string `json:"code"`
+		RecoveryCode     string `json:"recovery_code"`
+		ExpiresInSeconds int    `json:"expires_in_seconds,omitempty"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+	userID, err := auth.ValidateMFAToken(params.MFAToken, cfg.secret, time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid MFA token: "+err.Error())
+		return
+	}
+
+	user, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User not found")
+		return
+	}
+	totp, err := cfg.db.GetTOTPSecret(req.Context(), userID)
+	if err != nil || !totp.ConfirmedAt.Valid {
+		respondWithError(w, http.StatusUnauthorized, "Two-factor authentication is not enabled")
+		return
+	}
+	if err := cfg.checkSecondFactor(req.Context(), totp, params.Code, params.RecoveryCode); err != nil {
+		respondWithSecondFactorError(w, err)
+		return
+	}
+	cfg.logIn(w, req, user, params.ExpiresInSeconds)
+}
+
+// handlerEnrollTOTP starts turning on two-factor authentication. It has no
+// effect until the secret is confirmed, and calling it again before then
+// replaces the secret.
+func (cfg *apiConfig) handlerEnrollTOTP(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	user, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+
+	secret, err := auth.MakeTOTPSecret()
+	if err != nil {
+		respondWithError(w, 400, "Failed to create secret")
+		return
+	}
+	_, err = cfg.db.UpsertTOTPSecret(req.Context(), database.UpsertTOTPSecretParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, "Failed to save secret")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}{
+		Secret: secret,
+		URI:    auth.TOTPURI(secret, totpIssuer, user.Email),
+	})
+}
+
+// handlerConfirmTOTP turns on two-factor authentication once the user shows
+// their authenticator works, and responds with their recovery codes. This
+// is the only time the codes are shown.
+func (cfg *apiConfig) handlerConfirmTOTP(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	params := struct {
+		Code string `json:"code"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+
+	totp, err := cfg.db.GetTOTPSecret(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "Start enrollment first")
+		return
+	}
+	if totp.ConfirmedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	step, err := auth.ValidateTOTP(totp.Secret, params.Code, time.Now())
+	if err != nil {
+		respondWithError(w, 400, "Invalid code")
+		return
+	}
+	codes, err := auth.MakeRecoveryCodes()
+	if err != nil {
+		respondWithError(w, 400, "Failed to create recovery codes")
+		return
+	}
+	if err := cfg.confirmTOTP(req.Context(), userID, step, codes); err != nil {
+		respondWithError(w, 400, "Failed to enable two-factor authentication")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: codes,
+	})
+}
+
+func (cfg *apiConfig) confirmTOTP(ctx context.Context, userID uuid.UUID, step int64, codes []string) error {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := cfg.db.WithTx(tx)
+
+	confirmed, err := q.ConfirmTOTPSecret(ctx, database.ConfirmTOTPSecretParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return err
+	}
+	if confirmed == 0 {
+		return errors.New("already confirmed")
+	}
+	if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+	err = q.CreateRecoveryCodes(ctx, database.CreateRecoveryCodesParams{
+		UserID:     userID,
+		CodeHashes: hashes,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// handlerDisableTOTP turns two-factor authentication off. It takes a code,
+// or a recovery code, so a stolen access token alone can't do it.
+func (cfg *apiConfig) handlerDisableTOTP(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.authenticate(req)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+	params := struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "Failed to decode request")
+		return
+	}
+
+	totp, err := cfg.db.GetTOTPSecret(req.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "Two-factor authentication is not enabled")
+		return
+	}
+	// An unconfirmed secret never protected anything, so it can just go.
+	if totp.ConfirmedAt.Valid {
+		if err := cfg.checkSecondFactor(req.Context(), totp, params.Code, params.RecoveryCode); err != nil {
+			respondWithSecondFactorError(w, err)
+			return
+		}
+	}
+
+	if err := cfg.db.DeleteTOTPSecret(req.Context(), userID); err != nil {
+		respondWithError(w, 400, "Failed to disable two-factor authentication")
+		return
+	}
+	respondWithJSON(w, 204, struct{}{})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Who can read a chirp. Authors can always read their own.
+const (
+	visibilityPublic    = "public"
+	visibilityFollowers = "followers"
+	visibilityMentioned = "mentioned"
+)
+
+// parseVisibility checks the visibility given when creating a chirp. Chirps
+// are public unless asked otherwise.
+func parseVisibility(s string) (string, bool) {
+	switch s {
+	case "":
+		return visibilityPublic, true
+	case visibilityPublic, visibilityFollowers, visibilityMentioned:
+		return s, true
+	}
+	return "", false
+}
+
+// canView reports whether viewerID may read chirp. Public chirps are decided
+// without a query.
+func (cfg *apiConfig) canView(ctx context.Context, viewerID uuid.NullUUID, chirp database.Chirp) (bool, error) {
+	if chirp.Visibility == visibilityPublic {
+		return true, nil
+	}
+	if !viewerID.Valid {
+		return false, nil
+	}
+	return cfg.db.CanViewChirp(ctx, database.CanViewChirpParams{
+		ID:       chirp.ID,
+		ViewerID: viewerID,
+	})
+}
+
+// chirpVisible is canView for streams, which check every published chirp
+// against every subscriber and so can't afford a query each time. following
+// is the set of users the viewer followed when they subscribed.
+func chirpVisible(chirp Chirp, viewerID uuid.NullUUID, following map[uuid.UUID]bool) bool {
+	switch {
+	case chirp.Visibility == visibilityPublic:
+		return true
+	case !viewerID.Valid:
+		return false
+	case chirp.UserID == viewerID.UUID:
+		return true
+	case chirp.Visibility == visibilityFollowers:
+		return following[chirp.UserID]
+	case chirp.Visibility == visibilityMentioned:
+		return slices.ContainsFunc(chirp.Mentions, func(m mention) bool {
+			return m.UserID == viewerID.UUID
+		})
+	}
+	return false
+}
+
+// followingSet loads the IDs of the users userID follows.
+func (cfg *apiConfig) followingSet(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+	followeeIDs, err := cfg.db.ListFolloweeIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	following := make(map[uuid.UUID]bool, len(followeeIDs))
+	for _, id := range followeeIDs {
+		following[id] = true
+	}
+	return following, nil
+}
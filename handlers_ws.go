@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/brendenwelch/chirpy/internal/auth"
+	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/brendenwelch/chirpy/internal/pubsub"
+	"github.com/brendenwelch/chirpy/internal/websocket"
+	"github.com/google/uuid"
+)
+
+const (
+	wsTopicTimeline      = "timeline"
+	wsTopicThread        = "thread"
+	wsTopicNotifications = "notifications"
+
+	// wsMaxSubscriptions caps how many topics one connection can follow.
+	wsMaxSubscriptions = 10
+	// wsSendBuffer is how many messages may queue for a client before it is
+	// considered too slow and disconnected.
+	wsSendBuffer     = 64
+	wsWriteTimeout   = 10 * time.Second
+	wsPingInterval   = 30 * time.Second
+	wsReadTimeout    = 2 * wsPingInterval
+	wsTokenCheck     = 30 * time.Second
+	wsMaxMessageSize = 4 * 1024
+
+	// wsCloseTokenExpired tells the client to reconnect with a fresh token.
+	wsCloseTokenExpired = 4001
+)
+
+// wsRequest is a message from the client. "subscribe" and "unsubscribe" take
+// a topic, and an ID for threads. "auth" swaps in a fresh access token so the
+// connection can outlive the one it was opened with.
+type wsRequest struct {
+	Type  string    `json:"type"`
+	Topic string    `json:"topic"`
+	ID    uuid.UUID `json:"id"`
+	Token string    `json:"token"`
+}
+
+type wsResponse struct {
+	Type  string     `json:"type"`
+	Topic string     `json:"topic,omitempty"`
+	ID    *uuid.UUID `json:"id,omitempty"`
+	Data  any        `json:"data,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+type wsSession struct {
+	cfg    *apiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+	viewer *streamViewer
+
+	send      chan wsResponse
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu    sync.Mutex
+	token string
+	subs  map[string]func()
+}
+
+// wsRegistry tracks open sessions. Hijacked connections are invisible to
+// http.Server.Shutdown, so they are closed from here instead.
+type wsRegistry struct {
+	mu       sync.Mutex
+	sessions map[*wsSession]struct{}
+	closed   bool
+}
+
+// add registers s, and reports false once the server is shutting down.
+func (r *wsRegistry) add(s *wsSession) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false
+	}
+	if r.sessions == nil {
+		r.sessions = map[*wsSession]struct{}{}
+	}
+	r.sessions[s] = struct{}{}
+	return true
+}
+
+func (r *wsRegistry) remove(s *wsSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, s)
+}
+
+// Close closes every open session and turns away new ones.
+func (r *wsRegistry) Close() {
+	r.mu.Lock()
+	r.closed = true
+	sessions := r.sessions
+	r.sessions = nil
+	r.mu.Unlock()
+	for s := range sessions {
+		s.close(websocket.CloseGoingAway, "Server is shutting down")
+	}
+}
+
+// handlerWebSocket lets one connection follow the user's timeline, any number
+// of threads and their notifications. Browsers can't set headers on a
+// WebSocket, so the access token may also be given as access_token.
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, req *http.Request) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		token = req.URL.Query().Get("access_token")
+	}
+	userID, err := auth.ValidateJWT(token, cfg.secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token: "+err.Error())
+		return
+	}
+
+	// The viewer outlives the request, so it is tied to the session instead.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	viewer, err := cfg.newStreamViewer(ctx, userID)
+	if err != nil {
+		respondWithError(w, 400, "Failed to get followed, blocked and muted users")
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, req)
+	if err != nil {
+		return
+	}
+	conn.MaxMessageSize = wsMaxMessageSize
+	s := &wsSession{
+		cfg:    cfg,
+		conn:   conn,
+		userID: userID,
+		viewer: viewer,
+		send:   make(chan wsResponse, wsSendBuffer),
+		done:   make(chan struct{}),
+		token:  token,
+		subs:   map[string]func(){},
+	}
+	if !cfg.wsSessions.add(s) {
+		s.close(websocket.CloseGoingAway, "Server is shutting down")
+		return
+	}
+	defer cfg.wsSessions.remove(s)
+	defer s.unsubscribeAll()
+	go s.writeLoop()
+	s.readLoop()
+}
+
+func (s *wsSession) readLoop() {
+	defer s.close(websocket.CloseNormalClosure, "")
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType != websocket.TextMessage {
+			s.enqueue(wsResponse{Type: "error", Error: "Expected a JSON text message"})
+			continue
+		}
+		var msg wsRequest
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.enqueue(wsResponse{Type: "error", Error: "Failed to decode message"})
+			continue
+		}
+		switch msg.Type {
+		case "subscribe":
+			s.subscribe(msg.Topic, msg.ID)
+		case "unsubscribe":
+			s.unsubscribe(msg.Topic, msg.ID)
+		case "auth":
+			s.reauthenticate(msg.Token)
+		default:
+			s.enqueue(wsResponse{Type: "error", Error: "Unknown message type"})
+		}
+	}
+}
+
+// writeLoop is the only writer of data frames. It also keeps the connection
+// alive with pings and closes it once the access token expires.
+func (s *wsSession) writeLoop() {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	tokenCheck := time.NewTicker(wsTokenCheck)
+	defer tokenCheck.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg := <-s.send:
+			data, err := json.Marshal(msg)
+			if err != nil {
+				log.Printf("Failed to encode websocket message: %v", err)
+				continue
+			}
+			s.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := s.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				s.close(websocket.CloseGoingAway, "")
+				return
+			}
+		case <-ping.C:
+			s.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := s.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				s.close(websocket.CloseGoingAway, "")
+				return
+			}
+		case <-tokenCheck.C:
+			s.mu.Lock()
+			token := s.token
+			s.mu.Unlock()
+			if _, err := auth.ValidateJWT(token, s.cfg.secret); err != nil {
+				s.close(wsCloseTokenExpired, "Token expired")
+				return
+			}
+		}
+	}
+}
+
+// enqueue queues msg for the client. A client that can't keep up is
+// disconnected rather than allowed to hold messages in memory.
+func (s *wsSession) enqueue(msg wsResponse) {
+	select {
+	case s.send <- msg:
+	case <-s.done:
+	default:
+		s.close(websocket.CloseTryAgainLater, "Too slow")
+	}
+}
+
+func (s *wsSession) close(code int, reason string) {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		s.conn.WriteClose(code, reason)
+		s.conn.Close()
+	})
+}
+
+func (s *wsSession) reauthenticate(token string) {
+	userID, err := auth.ValidateJWT(token, s.cfg.secret)
+	if err != nil {
+		s.enqueue(wsResponse{Type: "error", Error: "Invalid token: " + err.Error()})
+		return
+	}
+	if userID != s.userID {
+		s.enqueue(wsResponse{Type: "error", Error: "Token is for a different user"})
+		return
+	}
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+	s.enqueue(wsResponse{Type: "authenticated"})
+}
+
+func wsSubscriptionKey(topic string, id uuid.UUID) string {
+	if topic == wsTopicThread {
+		return topic + ":" + id.String()
+	}
+	return topic
+}
+
+func (s *wsSession) subscribe(topic string, id uuid.UUID) {
+	reply := wsResponse{Type: "subscribed", Topic: topic}
+	if topic == wsTopicThread {
+		reply.ID = &id
+	}
+	key := wsSubscriptionKey(topic, id)
+	s.mu.Lock()
+	_, exists := s.subs[key]
+	count := len(s.subs)
+	s.mu.Unlock()
+	if exists {
+		s.enqueue(reply)
+		return
+	}
+	if count >= wsMaxSubscriptions {
+		s.enqueue(wsResponse{Type: "error", Topic: topic, Error: "Too many subscriptions"})
+		return
+	}
+
+	var cancel func()
+	var err error
+	switch topic {
+	case wsTopicTimeline:
+		cancel, err = s.subscribeTimeline()
+	case wsTopicThread:
+		cancel, err = s.subscribeThread(id)
+	case wsTopicNotifications:
+		cancel, err = subscribeTopic(s, s.cfg.notificationStream, "notification", topic, nil, func(n notification) bool {
+			return n.UserID == s.userID
+		})
+	default:
+		err = errors.New("Unknown topic")
+	}
+	if err != nil {
+		s.enqueue(wsResponse{Type: "error", Topic: topic, Error: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	s.subs[key] = cancel
+	s.mu.Unlock()
+	s.enqueue(reply)
+}
+
+// subscribeTimeline follows chirps from the accounts the user follows. Like
+// blocks and mutes, following or unfollowing someone applies as soon as it
+// is made.
+func (s *wsSession) subscribeTimeline() (func(), error) {
+	return subscribeTopic(s, s.cfg.chirpStream, "chirp", wsTopicTimeline, nil, func(chirp Chirp) bool {
+		return s.viewer.follows(chirp.UserID) && s.viewer.shows(chirp)
+	})
+}
+
+// subscribeThread follows new replies anywhere in the thread containing id,
+// leaving out those the user can't read or that are by anyone they have
+// blocked, been blocked by or muted.
+func (s *wsSession) subscribeThread(id uuid.UUID) (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), wsWriteTimeout)
+	defer cancel()
+	chirp, err := s.cfg.db.GetChirp(ctx, id)
+	if err != nil || chirp.DeletedAt.Valid {
+		return nil, errors.New("Chirp not found")
+	}
+	blocked, err := s.cfg.db.BlockExists(ctx, database.BlockExistsParams{
+		UserAID: s.userID,
+		UserBID: chirp.UserID,
+	})
+	if err != nil || blocked {
+		return nil, errors.New("Chirp not found")
+	}
+	visible, err := s.cfg.canView(ctx, uuid.NullUUID{UUID: s.userID, Valid: true}, chirp)
+	if err != nil || !visible {
+		return nil, errors.New("Chirp not found")
+	}
+	rootID := chirp.ID
+	if chirp.ThreadID.Valid {
+		rootID = chirp.ThreadID.UUID
+	}
+	return subscribeTopic(s, s.cfg.chirpStream, "chirp", wsTopicThread, &id, func(chirp Chirp) bool {
+		return chirp.ThreadID.Valid && chirp.ThreadID.UUID == rootID && s.viewer.shows(chirp)
+	})
+}
+
+// subscribeTopic forwards matching messages from a hub to the client until
+// the returned cancel func is called. If the hub gives up on the
+// subscription, because the client fell behind or the server is shutting
+// down, the connection is closed so the client knows to reconnect.
+func subscribeTopic[T any](s *wsSession, hub *pubsub.Hub[T], kind, topic string, id *uuid.UUID, filter func(T) bool) (func(), error) {
+	sub, err := hub.Subscribe(wsSendBuffer, filter)
+	if err != nil {
+		return nil, errors.New("Server is shutting down")
+	}
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-s.done:
+				return
+			case msg, ok := <-sub.C:
+				if !ok {
+					select {
+					case <-stop:
+						// Unsubscribed by the client.
+						return
+					default:
+					}
+					s.close(websocket.CloseTryAgainLater, "Subscription ended")
+					return
+				}
+				s.enqueue(wsResponse{Type: kind, Topic: topic, ID: id, Data: msg})
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		sub.Close()
+	}, nil
+}
+
+func (s *wsSession) unsubscribe(topic string, id uuid.UUID) {
+	key := wsSubscriptionKey(topic, id)
+	s.mu.Lock()
+	cancel, ok := s.subs[key]
+	delete(s.subs, key)
+	s.mu.Unlock()
+	if !ok {
+		s.enqueue(wsResponse{Type: "error", Topic: topic, Error: "Not subscribed"})
+		return
+	}
+	cancel()
+	reply := wsResponse{Type: "unsubscribed", Topic: topic}
+	if topic == wsTopicThread {
+		reply.ID = &id
+	}
+	s.enqueue(reply)
+}
+
+func (s *wsSession) unsubscribeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, cancel := range s.subs {
+		cancel()
+		delete(s.subs, key)
+	}
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -57,6 +59,46 @@ func ValidateJWT(tokenString string, tokenSecret string) (uuid.UUID, error) {
 	return id, nil
 }
 
+// MFA challenge tokens prove that a user got their password right and still
+// has to give a second factor. They have their own issuer, so they can never
+// be used as access tokens.
+const mfaIssuer = "chirpy-mfa"
+
+func MakeMFAToken(userID uuid.UUID, tokenSecret string, now time.Time, expiresIn time.Duration) (string, error) {
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    mfaIssuer,
+		IssuedAt:  jwt.NewNumericDate(now.UTC()),
+		ExpiresAt: jwt.NewNumericDate(now.UTC().Add(expiresIn)),
+		Subject:   userID.String(),
+	})
+	return tok.SignedString([]byte(tokenSecret))
+}
+
+// ValidateMFAToken returns the user an MFA challenge token was issued to, if
+// it is still valid at now.
+func ValidateMFAToken(tokenString string, tokenSecret string, now time.Time) (uuid.UUID, error) {
+	claims := &jwt.RegisteredClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithoutClaimsValidation())
+	_, err := parser.ParseWithClaims(tokenString, claims, func(tok *jwt.Token) (any, error) {
+		return []byte(tokenSecret), nil
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if claims.Issuer != mfaIssuer {
+		return uuid.Nil, errors.New("invalid issuer")
+	}
+	if !claims.VerifyExpiresAt(now.UTC(), true) {
+		return uuid.Nil, errors.New("token has expired")
+	}
+
+	id, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	return id, nil
+}
+
 func GetBearerToken(headers http.Header) (string, error) {
 	authHeader := headers.Get("Authorization")
 	if authHeader == "" {
@@ -74,6 +116,38 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(data), nil
 }
 
+// HashToken returns the form a random single-use token, such as a password
+// reset token, is stored in. It is random enough that a fast hash is safe.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+	// ErrRefreshTokenReused means a token that was already exchanged for a
+	// new one has been presented again. Only one of the two parties holding
+	// it can be its owner, so the caller should revoke its whole family.
+	ErrRefreshTokenReused = errors.New("refresh token already used")
+)
+
+// CheckRefreshToken reports whether a stored refresh token can still be
+// exchanged at now. rotatedAt is set once the token has been replaced by a
+// newer one.
+func CheckRefreshToken(now, expiresAt time.Time, revokedAt, rotatedAt sql.NullTime) error {
+	if rotatedAt.Valid {
+		return ErrRefreshTokenReused
+	}
+	if revokedAt.Valid {
+		return ErrRefreshTokenRevoked
+	}
+	if !now.Before(expiresAt) {
+		return ErrRefreshTokenExpired
+	}
+	return nil
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	authHeader := headers.Get("Authorization")
 	if authHeader == "" {
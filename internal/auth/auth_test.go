@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -39,3 +41,62 @@ func TestValidateJWT(t *testing.T) {
 		}
 	})
 }
+
+func TestCheckRefreshToken(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+	earlier := sql.NullTime{Time: now.Add(-time.Minute), Valid: true}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		revokedAt sql.NullTime
+		rotatedAt sql.NullTime
+		want      error
+	}{
+		{name: "Valid token", now: now},
+		{name: "Expired token", now: expiresAt, want: ErrRefreshTokenExpired},
+		{name: "Revoked token", now: now, revokedAt: earlier, want: ErrRefreshTokenRevoked},
+		// Rotation revokes the old token too, but reuse takes precedence.
+		{name: "Rotated token", now: now, revokedAt: earlier, rotatedAt: earlier, want: ErrRefreshTokenReused},
+		{name: "Rotated and expired token", now: expiresAt.Add(time.Hour), revokedAt: earlier, rotatedAt: earlier, want: ErrRefreshTokenReused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRefreshToken(tt.now, expiresAt, tt.revokedAt, tt.rotatedAt)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("CheckRefreshToken() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeRefreshToken(t *testing.T) {
+	a, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken() error = %v", err)
+	}
+	b, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken() error = %v", err)
+	}
+	if len(a) != 64 {
+		t.Errorf("MakeRefreshToken() length = %d, want 64", len(a))
+	}
+	if a == b {
+		t.Error("MakeRefreshToken() returned the same token twice")
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	a, b := HashToken("token-a"), HashToken("token-b")
+	if a == b {
+		t.Error("HashToken() gave two tokens the same hash")
+	}
+	if a != HashToken("token-a") {
+		t.Error("HashToken() is not deterministic")
+	}
+	if a == "token-a" {
+		t.Error("HashToken() returned the token itself")
+	}
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP as in RFC 6238, with the parameters every authenticator app supports:
+// HMAC-SHA1, six digits and a 30 second period.
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30 * time.Second
+
+	// totpSkew is how many periods either side of now a code is still
+	// accepted for, to allow for clocks that have drifted a little.
+	totpSkew       = 1
+	totpSecretSize = 20
+
+	RecoveryCodeCount = 10
+	recoveryCodeSize  = 10
+)
+
+var ErrInvalidTOTPCode = errors.New("invalid code")
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MakeTOTPSecret returns a new random secret, base32 encoded as authenticator
+// apps expect.
+func MakeTOTPSecret() (string, error) {
+	data := make([]byte, totpSecretSize)
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate random data: %v", err)
+	}
+	return totpEncoding.EncodeToString(data), nil
+}
+
+// TOTPURI returns the otpauth:// URI that authenticator apps import, usually
+// by scanning it as a QR code.
+func TOTPURI(secret, issuer, account string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(int(TOTPPeriod.Seconds())))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// TOTPCode returns the code for secret at now.
+func TOTPCode(secret string, now time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpCode(key, totpStep(now)), nil
+}
+
+// ValidateTOTP checks code against secret at now, and returns the time step
+// it belongs to. A code stays valid for a little while, so to make each one
+// work only once callers should refuse any step at or before the last one
+// they accepted.
+func ValidateTOTP(secret, code string, now time.Time) (int64, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return 0, err
+	}
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != TOTPDigits {
+		return 0, ErrInvalidTOTPCode
+	}
+	step := totpStep(now)
+	for i := int64(-totpSkew); i <= totpSkew; i++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step+i)), []byte(code)) == 1 {
+			return step + i, nil
+		}
+	}
+	return 0, ErrInvalidTOTPCode
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return key, nil
+}
+
+func totpStep(now time.Time) int64 {
+	return now.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+// totpCode is the HOTP value (RFC 4226) of key for counter.
+func totpCode(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for range TOTPDigits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod)
+}
+
+// MakeRecoveryCodes returns a fresh set of one-time codes for users who lose
+// their authenticator.
+func MakeRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		data := make([]byte, recoveryCodeSize*5/8)
+		_, err := rand.Read(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate random data: %v", err)
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(data))
+		codes[i] = code[:recoveryCodeSize/2] + "-" + code[recoveryCodeSize/2:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the form a recovery code is stored in. The codes
+// are random enough that a fast hash is safe, and it lets them be looked up
+// directly. Case, spaces and dashes are ignored.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
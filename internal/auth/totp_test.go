@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"encoding/base32"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The SHA1 test vectors from RFC 6238, appendix B, cut down to six digits.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode() at %d = %v, want %v", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := TOTPCode(rfcSecret, now)
+
+	t.Run("Current code", func(t *testing.T) {
+		step, err := ValidateTOTP(rfcSecret, code, now)
+		if err != nil {
+			t.Fatalf("ValidateTOTP() error = %v", err)
+		}
+		if step != now.Unix()/30 {
+			t.Errorf("ValidateTOTP() step = %v, want %v", step, now.Unix()/30)
+		}
+	})
+
+	t.Run("Previous period", func(t *testing.T) {
+		step, err := ValidateTOTP(rfcSecret, code, now.Add(TOTPPeriod))
+		if err != nil {
+			t.Fatalf("ValidateTOTP() error = %v", err)
+		}
+		if step != now.Unix()/30 {
+			t.Errorf("ValidateTOTP() step = %v, want %v", step, now.Unix()/30)
+		}
+	})
+
+	t.Run("Too old", func(t *testing.T) {
+		_, err := ValidateTOTP(rfcSecret, code, now.Add(2*TOTPPeriod))
+		if !errors.Is(err, ErrInvalidTOTPCode) {
+			t.Errorf("ValidateTOTP() error = %v, want %v", err, ErrInvalidTOTPCode)
+		}
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		_, err := ValidateTOTP(rfcSecret, "000000", now)
+		if !errors.Is(err, ErrInvalidTOTPCode) {
+			t.Errorf("ValidateTOTP() error = %v, want %v", err, ErrInvalidTOTPCode)
+		}
+	})
+
+	t.Run("Wrong length", func(t *testing.T) {
+		_, err := ValidateTOTP(rfcSecret, code[:5], now)
+		if !errors.Is(err, ErrInvalidTOTPCode) {
+			t.Errorf("ValidateTOTP() error = %v, want %v", err, ErrInvalidTOTPCode)
+		}
+	})
+
+	t.Run("Invalid secret", func(t *testing.T) {
+		_, err := ValidateTOTP("not base32!", code, now)
+		if err == nil {
+			t.Error("ValidateTOTP() expected error")
+		}
+	})
+}
+
+func TestMakeTOTPSecret(t *testing.T) {
+	secret, err := MakeTOTPSecret()
+	if err != nil {
+		t.Fatalf("MakeTOTPSecret() error = %v", err)
+	}
+	now := time.Now()
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("TOTPCode() error = %v", err)
+	}
+	if _, err := ValidateTOTP(secret, code, now); err != nil {
+		t.Errorf("ValidateTOTP() error = %v", err)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("JBSWY3DPEHPK3PXP", "Chirpy", "user@example.com")
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("url.Parse() error = %v", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Chirpy:user@example.com" {
+		t.Errorf("TOTPURI() = %v", uri)
+	}
+	query := u.Query()
+	if query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "Chirpy" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("TOTPURI() = %v", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := MakeRecoveryCodes()
+	if err != nil {
+		t.Fatalf("MakeRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("MakeRecoveryCodes() returned %d codes, want %d", len(codes), RecoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if seen[code] {
+			t.Errorf("MakeRecoveryCodes() returned %v twice", code)
+		}
+		seen[code] = true
+	}
+
+	code := codes[0]
+	if HashRecoveryCode(code) != HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", " "))) {
+		t.Error("HashRecoveryCode() depends on case or separators")
+	}
+	if HashRecoveryCode(codes[0]) == HashRecoveryCode(codes[1]) {
+		t.Error("HashRecoveryCode() gave two codes the same hash")
+	}
+}
+
+func TestValidateMFAToken(t *testing.T) {
+	userID := uuid.New()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tok, err := MakeMFAToken(userID, "secret", now, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("MakeMFAToken() error = %v", err)
+	}
+
+	t.Run("Valid token", func(t *testing.T) {
+		id, err := ValidateMFAToken(tok, "secret", now.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("ValidateMFAToken() error = %v", err)
+		}
+		if id != userID {
+			t.Errorf("ValidateMFAToken() wrong userID = %v", id)
+		}
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		_, err := ValidateMFAToken(tok, "secret", now.Add(6*time.Minute))
+		if err == nil {
+			t.Error("ValidateMFAToken() expected error")
+		}
+	})
+
+	t.Run("Wrong secret", func(t *testing.T) {
+		_, err := ValidateMFAToken(tok, "wrong", now)
+		if err == nil {
+			t.Error("ValidateMFAToken() expected error")
+		}
+	})
+
+	t.Run("Not an access token", func(t *testing.T) {
+		// ValidateJWT checks expiry against the real clock.
+		tok, _ := MakeMFAToken(userID, "secret", time.Now(), time.Hour)
+		if _, err := ValidateJWT(tok, "secret"); err == nil {
+			t.Error("ValidateJWT() accepted an MFA token")
+		}
+	})
+
+	t.Run("Access token is not an MFA token", func(t *testing.T) {
+		access, _ := MakeJWT(userID, "secret", time.Hour)
+		if _, err := ValidateMFAToken(access, "secret", time.Now()); err == nil {
+			t.Error("ValidateMFAToken() accepted an access token")
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package chirptext
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Hashtags returns the distinct hashtags in body, lowercased and without the
+// leading #, in the order they first appear. A tag is a # followed by letters,
+// digits or underscores, and only counts at the start of a word, so "a#b" and
+// "#" alone are not tags.
+func Hashtags(body string) []string {
+	return entities(body, '#')
+}
+
+// Mentions returns the distinct handles @mentioned in body, lowercased and
+// without the leading @, following the same rules as Hashtags. An email
+// address is not a mention because its @ is in the middle of a word.
+func Mentions(body string) []string {
+	return entities(body, '@')
+}
+
+// ValidHandle reports whether handle can be used as a user handle. Handles are
+// what Mentions extracts, so they are limited to characters that can appear in
+// a mention.
+func ValidHandle(handle string) bool {
+	if len(handle) < 3 || len(handle) > 15 {
+		return false
+	}
+	for _, r := range handle {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func entities(body string, sigil rune) []string {
+	var found []string
+	seen := map[string]struct{}{}
+	prev := ' '
+	for i, r := range body {
+		if r == sigil && !isWordRune(prev) {
+			rest := body[i+utf8.RuneLen(r):]
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return !isWordRune(r)
+			})
+			if end == -1 {
+				end = len(rest)
+			}
+			if end > 0 {
+				name := strings.ToLower(rest[:end])
+				if _, ok := seen[name]; !ok {
+					seen[name] = struct{}{}
+					found = append(found, name)
+				}
+			}
+		}
+		prev = r
+	}
+	return found
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,76 @@
+package chirptext
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "No tags", body: "just chirping", want: nil},
+		{name: "Single tag", body: "loving #Go today", want: []string{"go"}},
+		{name: "Punctuation ends a tag", body: "#golang, #sqlc!", want: []string{"golang", "sqlc"}},
+		{name: "Duplicates", body: "#go #GO #go", want: []string{"go"}},
+		{name: "Mid-word is not a tag", body: "issue#42 and a#b", want: nil},
+		{name: "Bare sigil", body: "# #", want: nil},
+		{name: "Underscores and unicode", body: "#chirpy_red #café", want: []string{"chirpy_red", "café"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hashtags(tt.body)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Hashtags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "Single mention", body: "hi @Alice!", want: []string{"alice"}},
+		{name: "Several mentions", body: "@bob and @carol_1, @bob", want: []string{"bob", "carol_1"}},
+		{name: "Email address", body: "mail me at bob@example.com", want: nil},
+		{name: "Hashtags are not mentions", body: "#bob", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mentions(tt.body)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Mentions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidHandle(t *testing.T) {
+	tests := []struct {
+		handle string
+		want   bool
+	}{
+		{handle: "bob", want: true},
+		{handle: "chirpy_fan_2024", want: true},
+		{handle: "bo", want: false},
+		{handle: "a_very_long_handle", want: false},
+		{handle: "Bob", want: false},
+		{handle: "bob.smith", want: false},
+		{handle: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.handle, func(t *testing.T) {
+			if got := ValidHandle(tt.handle); got != tt.want {
+				t.Errorf("ValidHandle(%q) = %v, want %v", tt.handle, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,29 +7,125 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const canViewChirp = `-- name: CanViewChirp :one
+SELECT EXISTS (
+	SELECT 1 FROM chirps
+	WHERE id = $1
+	AND (
+		chirps.visibility = 'public'
+		OR chirps.user_id = $2::uuid
+		OR (chirps.visibility = 'followers' AND EXISTS (
+			SELECT 1 FROM follows WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id
+		))
+		OR (chirps.visibility = 'mentioned' AND EXISTS (
+			SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = $2::uuid
+		))
+	)
+)
+`
+
+type CanViewChirpParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) CanViewChirp(ctx context.Context, arg CanViewChirpParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, canViewChirp, arg.ID, arg.ViewerID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const countChirpReferences = `-- name: CountChirpReferences :one
+SELECT count(*) FROM chirps
+WHERE parent_id = $1 OR original_id = $1
+`
+
+func (q *Queries) CountChirpReferences(ctx context.Context, id uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpReferences, id)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_id, thread_id, original_id, visibility)
 VALUES (
 	gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3,
+	$4,
+	$5,
+	$6
 	)
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, parent_id, thread_id, deleted_at, original_id, is_rechirp, visibility
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body       string
+	UserID     uuid.UUID
+	ParentID   uuid.NullUUID
+	ThreadID   uuid.NullUUID
+	OriginalID uuid.NullUUID
+	Visibility string
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ParentID,
+		arg.ThreadID,
+		arg.OriginalID,
+		arg.Visibility,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.ThreadID,
+		&i.DeletedAt,
+		&i.OriginalID,
+		&i.IsRechirp,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, original_id, is_rechirp)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	NOW(),
+	'',
+	$1,
+	$2,
+	true
+	)
+RETURNING id, created_at, updated_at, body, user_id, parent_id, thread_id, deleted_at, original_id, is_rechirp, visibility
+`
+
+type CreateRechirpParams struct {
+	UserID     uuid.UUID
+	OriginalID uuid.UUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.OriginalID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +133,12 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.ThreadID,
+		&i.DeletedAt,
+		&i.OriginalID,
+		&i.IsRechirp,
+		&i.Visibility,
 	)
 	return i, err
 }
@@ -50,12 +152,71 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+const editChirp = `-- name: EditChirp :one
+WITH revision AS (
+	INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+	SELECT gen_random_uuid(), id, body, updated_at, NOW()
+	FROM chirps WHERE id = $1
+)
+UPDATE chirps
+SET updated_at = NOW(), body = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, parent_id, thread_id, deleted_at, original_id, is_rechirp, visibility
+`
+
+type EditChirpParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) EditChirp(ctx context.Context, arg EditChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, editChirp, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.ThreadID,
+		&i.DeletedAt,
+		&i.OriginalID,
+		&i.IsRechirp,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getChirp = `-- name: GetChirp :one
+SELECT id, created_at, updated_at, body, user_id, parent_id, thread_id, deleted_at, original_id, is_rechirp, visibility FROM chirps WHERE id = $1
+`
+
+func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.ThreadID,
+		&i.DeletedAt,
+		&i.OriginalID,
+		&i.IsRechirp,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, thread_id, deleted_at, original_id, is_rechirp, visibility FROM chirps WHERE id = ANY($1::uuid[])
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
@@ -69,6 +230,12 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.OriginalID,
+			&i.IsRechirp,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -83,12 +250,37 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getAllChirpsByUser = `-- name: GetAllChirpsByUser :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
+const getThread = `-- name: GetThread :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, thread_id, deleted_at, original_id, is_rechirp, visibility FROM chirps
+WHERE (id = $1 OR thread_id = $1)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2::uuid)
+	OR (blocks.blocker_id = $2::uuid AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = $2::uuid AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = $2::uuid
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = $2::uuid
+	))
+)
+ORDER BY created_at ASC, id ASC
 `
 
-func (q *Queries) GetAllChirpsByUser(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirpsByUser, userID)
+type GetThreadParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetThread(ctx context.Context, arg GetThreadParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getThread, arg.ID, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +294,12 @@ func (q *Queries) GetAllChirpsByUser(ctx context.Context, userID uuid.UUID) ([]C
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.OriginalID,
+			&i.IsRechirp,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -116,21 +314,239 @@ func (q *Queries) GetAllChirpsByUser(ctx context.Context, userID uuid.UUID) ([]C
 	return items, nil
 }
 
-const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, thread_id, deleted_at, original_id, is_rechirp, visibility FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2::uuid)
+	OR (blocks.blocker_id = $2::uuid AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = $2::uuid AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = $2::uuid
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = $2::uuid
+	))
+)
+AND (
+	$3::timestamp IS NULL
+	OR (created_at, id) > ($3, $4::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT $5
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
-	var i Chirp
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Body,
-		&i.UserID,
+type ListChirpsAscParams struct {
+	AuthorID        uuid.NullUUID
+	ViewerID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
 	)
-	return i, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.OriginalID,
+			&i.IsRechirp,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, thread_id, deleted_at, original_id, is_rechirp, visibility FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2::uuid)
+	OR (blocks.blocker_id = $2::uuid AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = $2::uuid AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = $2::uuid
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = $2::uuid
+	))
+)
+AND (
+	$3::timestamp IS NULL
+	OR (created_at, id) < ($3, $4::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type ListChirpsDescParams struct {
+	AuthorID        uuid.NullUUID
+	ViewerID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.OriginalID,
+			&i.IsRechirp,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimeline = `-- name: ListTimeline :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, thread_id, deleted_at, original_id, is_rechirp, visibility FROM chirps
+WHERE deleted_at IS NULL
+AND user_id IN (SELECT followee_id FROM follows WHERE follower_id = $1)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $1)
+	OR (blocks.blocker_id = $1 AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = $1 AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = $1
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = $1 AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = $1
+	))
+)
+AND (
+	$2::timestamp IS NULL
+	OR (created_at, id) < ($2, $3::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListTimelineParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListTimeline(ctx context.Context, arg ListTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listTimeline,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.OriginalID,
+			&i.IsRechirp,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const resetChirps = `-- name: ResetChirps :exec
@@ -141,3 +557,21 @@ func (q *Queries) ResetChirps(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetChirps)
 	return err
 }
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+WITH revisions AS (
+	DELETE FROM chirp_revisions WHERE chirp_id = $1
+), bookmarks AS (
+	DELETE FROM bookmarks WHERE chirp_id = $1
+), pins AS (
+	DELETE FROM pins WHERE chirp_id = $1
+)
+UPDATE chirps
+SET updated_at = NOW(), body = '', deleted_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
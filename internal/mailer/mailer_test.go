@@ -0,0 +1,206 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"user@example.com", true},
+		{"first.last+tag@mail.example.co.uk", true},
+		{"", false},
+		{"user", false},
+		{"user@", false},
+		{"@example.com", false},
+		{"user@localhost", false},
+		{"user@example.", false},
+		{"user@@example.com", false},
+		{"user @example.com", false},
+		{"User <user@example.com>", false},
+		{"user@example.com (comment)", false},
+		{`"user name"@example.com`, false},
+		{"user@example.com\r\nBcc: victim@example.com", false},
+		{strings.Repeat("a", 250) + "@example.com", false},
+	}
+	for _, tt := range tests {
+		if got := ValidAddress(tt.addr); got != tt.want {
+			t.Errorf("ValidAddress(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	data, err := format("chirpy@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Reset your password",
+		Body:    "Line one\nLine two",
+	}, date)
+	if err != nil {
+		t.Fatalf("format() error = %v", err)
+	}
+	want := "From: chirpy@example.com\r\n" +
+		"To: user@example.com\r\n" +
+		"Subject: Reset your password\r\n" +
+		"Date: Wed, 01 Jan 2025 12:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Line one\r\nLine two\r\n"
+	if string(data) != want {
+		t.Errorf("format() = %q, want %q", data, want)
+	}
+}
+
+func TestFormatRejectsHeaderInjection(t *testing.T) {
+	tests := []Message{
+		{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi"},
+		{To: "user@example.com", Subject: "Hi\nBcc: victim@example.com"},
+	}
+	for _, msg := range tests {
+		if _, err := format("chirpy@example.com", msg, time.Now()); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("format(%q) error = %v, want %v", msg, err, ErrInvalidHeader)
+		}
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var b bytes.Buffer
+	m := NewLogMailer(&b, "chirpy@example.com")
+	err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Hi", Body: "Hello"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	out := b.String()
+	if !strings.Contains(out, "To: user@example.com\r\n") || !strings.HasSuffix(out, "Hello\r\n\r\n.\r\n") {
+		t.Errorf("Send() wrote %q", out)
+	}
+}
+
+// fakeSMTPServer accepts one message on a local port and sends its data to
+// the returned channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ready")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 Go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	m, err := NewSMTPMailer(addr, "", "", "chirpy@example.com")
+	if err != nil {
+		t.Fatalf("NewSMTPMailer() error = %v", err)
+	}
+	err = m.Send(context.Background(), Message{To: "user@example.com", Subject: "Hi", Body: "Hello"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	select {
+	case data := <-received:
+		if !strings.Contains(data, "To: user@example.com\r\n") || !strings.HasSuffix(data, "\r\nHello\r\n") {
+			t.Errorf("server received %q", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server received nothing")
+	}
+}
+
+func TestSMTPMailerTimeout(t *testing.T) {
+	// A server that accepts connections but never says anything.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	m, err := NewSMTPMailer(l.Addr().String(), "", "", "chirpy@example.com")
+	if err != nil {
+		t.Fatalf("NewSMTPMailer() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Send(ctx, Message{To: "user@example.com", Subject: "Hi", Body: "Hello"})
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Send() expected error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send() didn't give up when the context expired")
+	}
+}
+
+func TestNewSMTPMailerInvalidAddress(t *testing.T) {
+	if _, err := NewSMTPMailer("localhost", "", "", "chirpy@example.com"); err == nil {
+		t.Error("NewSMTPMailer() expected error")
+	}
+}
@@ -0,0 +1,132 @@
+// Package media prepares uploaded images for publishing. Every image is
+// decoded and encoded again, which drops EXIF and any other metadata, and a
+// thumbnail is made alongside it.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxUploadSize is the largest file accepted, in bytes.
+	MaxUploadSize = 5 << 20
+	// MaxPixels bounds the decoded size, so a small file can't expand into
+	// an enormous image.
+	MaxPixels = 24_000_000
+	// ThumbnailSize is the longest side of a thumbnail, in pixels.
+	ThumbnailSize = 320
+
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooLarge        = errors.New("image is too large")
+)
+
+type Encoded struct {
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+type Processed struct {
+	Image     Encoded
+	Thumbnail Encoded
+}
+
+// SniffContentType identifies an upload by its contents, ignoring whatever
+// type the client claimed. Only JPEG, PNG and GIF are accepted.
+func SniffContentType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return contentType, nil
+	}
+	return "", ErrUnsupportedType
+}
+
+// Extension is the file extension for a content type Process produces.
+func Extension(contentType string) string {
+	if contentType == "image/jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// Process re-encodes an uploaded image and makes its thumbnail. JPEGs are
+// turned upright according to their EXIF orientation before it's dropped.
+// JPEGs stay JPEGs; everything else becomes a PNG, so animated GIFs keep
+// only their first frame.
+func Process(data []byte) (Processed, error) {
+	if len(data) > MaxUploadSize {
+		return Processed{}, ErrTooLarge
+	}
+	contentType, err := SniffContentType(data)
+	if err != nil {
+		return Processed{}, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUnsupportedType
+	}
+	if config.Width*config.Height > MaxPixels {
+		return Processed{}, ErrTooLarge
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUnsupportedType
+	}
+
+	img := toRGBA(decoded)
+	if contentType == "image/jpeg" {
+		img = orient(img, jpegOrientation(data))
+	} else {
+		contentType = "image/png"
+	}
+
+	var processed Processed
+	processed.Image, err = encode(img, contentType)
+	if err != nil {
+		return Processed{}, err
+	}
+	processed.Thumbnail, err = encode(thumbnail(img, ThumbnailSize), contentType)
+	if err != nil {
+		return Processed{}, err
+	}
+	return processed, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+func encode(img *image.RGBA, contentType string) (Encoded, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return Encoded{}, err
+	}
+	return Encoded{
+		ContentType: contentType,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Data:        buf.Bytes(),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF segment with the given orientation right
+// after the start of a JPEG.
+func withOrientation(jpegData []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	out := []byte{0xff, 0xd8, 0xff, 0xe1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("PNG", func(t *testing.T) {
+		processed, err := Process(encodePNG(t, testImage(640, 480)))
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		if processed.Image.ContentType != "image/png" || processed.Image.Width != 640 || processed.Image.Height != 480 {
+			t.Errorf("Process() image = %v %vx%v", processed.Image.ContentType, processed.Image.Width, processed.Image.Height)
+		}
+		if processed.Thumbnail.Width != ThumbnailSize || processed.Thumbnail.Height != 240 {
+			t.Errorf("Process() thumbnail = %vx%v, want %vx240", processed.Thumbnail.Width, processed.Thumbnail.Height, ThumbnailSize)
+		}
+	})
+
+	t.Run("JPEG is turned upright and loses its EXIF", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, testImage(40, 20), nil); err != nil {
+			t.Fatal(err)
+		}
+		data := withOrientation(buf.Bytes(), 6)
+		if got := jpegOrientation(data); got != 6 {
+			t.Fatalf("jpegOrientation() = %v, want 6", got)
+		}
+
+		processed, err := Process(data)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+		if processed.Image.ContentType != "image/jpeg" || processed.Image.Width != 20 || processed.Image.Height != 40 {
+			t.Errorf("Process() image = %v %vx%v, want image/jpeg 20x40", processed.Image.ContentType, processed.Image.Width, processed.Image.Height)
+		}
+		if bytes.Contains(processed.Image.Data, []byte("Exif")) {
+			t.Error("Process() kept the EXIF segment")
+		}
+	})
+
+	t.Run("Not an image", func(t *testing.T) {
+		if _, err := Process([]byte("<html><body>hi</body></html>")); err != ErrUnsupportedType {
+			t.Errorf("Process() error = %v, want %v", err, ErrUnsupportedType)
+		}
+	})
+
+	t.Run("Too many pixels", func(t *testing.T) {
+		data := encodePNG(t, testImage(1, 1))
+		// Claim a huge size in the IHDR chunk and fix up its checksum.
+		binary.BigEndian.PutUint32(data[16:], 100_000)
+		binary.BigEndian.PutUint32(data[20:], 100_000)
+		binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+		if _, err := Process(data); err != ErrTooLarge {
+			t.Errorf("Process() error = %v, want %v", err, ErrTooLarge)
+		}
+	})
+}
+
+func TestOrient(t *testing.T) {
+	// A 2x1 image: red on the left, blue on the right.
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		want        [][]color.RGBA
+	}{
+		{1, [][]color.RGBA{{red, blue}}},
+		{2, [][]color.RGBA{{blue, red}}},
+		{3, [][]color.RGBA{{blue, red}}},
+		{4, [][]color.RGBA{{red, blue}}},
+		{5, [][]color.RGBA{{red}, {blue}}},
+		{6, [][]color.RGBA{{red}, {blue}}},
+		{7, [][]color.RGBA{{blue}, {red}}},
+		{8, [][]color.RGBA{{blue}, {red}}},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.orientation)
+		for y, row := range tt.want {
+			for x, want := range row {
+				if c := got.RGBAAt(x, y); c != want {
+					t.Errorf("orient(%v) at %v,%v = %v, want %v", tt.orientation, x, y, c, want)
+				}
+			}
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	small := testImage(10, 10)
+	if got := thumbnail(small, 20); got != small {
+		t.Error("thumbnail() should not scale up")
+	}
+
+	got := thumbnail(testImage(100, 1000), 50)
+	if got.Bounds().Dx() != 5 || got.Bounds().Dy() != 50 {
+		t.Errorf("thumbnail() = %v, want 5x50", got.Bounds())
+	}
+}
@@ -0,0 +1,84 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage keeps processed media. Keys are file names, such as an ID with an
+// extension, never paths.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[a-z0-9]+)?$`)
+
+// DiskStorage stores media as files in one directory.
+type DiskStorage struct {
+	dir string
+}
+
+func NewDiskStorage(dir string) (*DiskStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskStorage{dir: dir}, nil
+}
+
+func (s *DiskStorage) path(key string) (string, error) {
+	if !validKey.MatchString(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+// Put writes to a temporary file first, so a file is never seen half
+// written.
+func (s *DiskStorage) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open returns an error satisfying errors.Is(err, fs.ErrNotExist) for
+// missing keys.
+func (s *DiskStorage) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *DiskStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+func TestDiskStorage(t *testing.T) {
+	ctx := context.Background()
+	storage, err := NewDiskStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.Put(ctx, "abc.png", []byte("data")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	f, err := storage.Open(ctx, "abc.png")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil || string(data) != "data" {
+		t.Errorf("Open() read %q, %v", data, err)
+	}
+
+	if err := storage.Delete(ctx, "abc.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := storage.Open(ctx, "abc.png"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open() after Delete() error = %v, want not exist", err)
+	}
+
+	for _, key := range []string{"../escape.png", "a/b.png", "", ".hidden"} {
+		if err := storage.Put(ctx, key, nil); err != ErrInvalidKey {
+			t.Errorf("Put(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
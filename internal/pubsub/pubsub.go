@@ -0,0 +1,95 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrClosed = errors.New("hub closed")
+
+// Hub fans messages out to every subscriber in the process. Publishing never
+// blocks: a subscriber that falls a full buffer behind is dropped, and its
+// channel is closed so it can notice and catch up some other way.
+type Hub[T any] struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription[T]]struct{}
+	closed      bool
+}
+
+type Subscription[T any] struct {
+	// C receives every published message that passes the filter. It is
+	// closed when the subscription ends for any reason.
+	C <-chan T
+
+	c      chan T
+	filter func(T) bool
+	hub    *Hub[T]
+}
+
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{
+		subscribers: map[*Subscription[T]]struct{}{},
+	}
+}
+
+// Subscribe starts receiving messages for which filter returns true, or every
+// message if filter is nil. buffer is how many messages may be waiting before
+// the subscriber is considered too slow and dropped.
+func (h *Hub[T]) Subscribe(buffer int, filter func(T) bool) (*Subscription[T], error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	c := make(chan T, buffer)
+	sub := &Subscription[T]{
+		C:      c,
+		c:      c,
+		filter: filter,
+		hub:    h,
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+func (h *Hub[T]) Publish(msg T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if sub.filter != nil && !sub.filter(msg) {
+			continue
+		}
+		select {
+		case sub.c <- msg:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Close ends every subscription and rejects new ones.
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
+
+// remove must be called with h.mu held.
+func (h *Hub[T]) remove(sub *Subscription[T]) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	close(sub.c)
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription[T]) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
@@ -0,0 +1,68 @@
+package pubsub
+
+import (
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+	hub := NewHub[int]()
+	all, _ := hub.Subscribe(4, nil)
+	even, _ := hub.Subscribe(4, func(n int) bool { return n%2 == 0 })
+
+	for n := range 3 {
+		hub.Publish(n)
+	}
+
+	for _, want := range []int{0, 1, 2} {
+		if got := <-all.C; got != want {
+			t.Errorf("all received %v, want %v", got, want)
+		}
+	}
+	for _, want := range []int{0, 2} {
+		if got := <-even.C; got != want {
+			t.Errorf("even received %v, want %v", got, want)
+		}
+	}
+	if len(even.C) != 0 {
+		t.Errorf("even received %v filtered messages", len(even.C))
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	hub := NewHub[int]()
+	slow, _ := hub.Subscribe(1, nil)
+	fast, _ := hub.Subscribe(2, nil)
+
+	hub.Publish(1)
+	hub.Publish(2)
+
+	if got := <-slow.C; got != 1 {
+		t.Errorf("slow received %v, want 1", got)
+	}
+	if _, ok := <-slow.C; ok {
+		t.Error("slow subscriber was not dropped")
+	}
+	if len(fast.C) != 2 {
+		t.Errorf("fast has %v messages waiting, want 2", len(fast.C))
+	}
+}
+
+func TestClose(t *testing.T) {
+	hub := NewHub[int]()
+	sub, _ := hub.Subscribe(1, nil)
+	sub.Close()
+	sub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Error("subscription channel still open after Close")
+	}
+
+	other, _ := hub.Subscribe(1, nil)
+	hub.Close()
+	if _, ok := <-other.C; ok {
+		t.Error("subscription channel still open after hub Close")
+	}
+	if _, err := hub.Subscribe(1, nil); err != ErrClosed {
+		t.Errorf("Subscribe() after Close error = %v, want %v", err, ErrClosed)
+	}
+	hub.Publish(1)
+}
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ToTSQuery turns a user's search string into the to_tsquery syntax Postgres
+// expects. Every term must match. "Quoted words" must appear as a phrase, a
+// trailing * makes a word match as a prefix, and a leading - excludes a word.
+// Punctuation is dropped so user input can never produce a syntax error.
+func ToTSQuery(query string) (string, error) {
+	var terms []string
+	rest := query
+	for rest != "" {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+
+		if rest[0] == '"' {
+			phrase, after, found := strings.Cut(rest[1:], `"`)
+			if !found {
+				after = ""
+			}
+			rest = after
+			var words []string
+			for _, word := range strings.Fields(phrase) {
+				if word = sanitize(word); word != "" {
+					words = append(words, word)
+				}
+			}
+			if len(words) > 0 {
+				terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+			}
+			continue
+		}
+
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end == -1 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+
+		negate := strings.HasPrefix(word, "-")
+		prefix := strings.HasSuffix(word, "*")
+		word = sanitize(word)
+		if word == "" {
+			continue
+		}
+		if prefix {
+			word += ":*"
+		}
+		if negate {
+			word = "!" + word
+		}
+		terms = append(terms, word)
+	}
+
+	if len(terms) == 0 {
+		return "", errors.New("no search terms")
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+func sanitize(word string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, word)
+}
@@ -0,0 +1,35 @@
+package search
+
+import "testing"
+
+func TestToTSQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Single word", query: "chirpy", want: "chirpy"},
+		{name: "All words must match", query: "hello  world", want: "hello & world"},
+		{name: "Phrase", query: `"hello big world" bye`, want: "(hello <-> big <-> world) & bye"},
+		{name: "Unterminated phrase", query: `"hello world`, want: "(hello <-> world)"},
+		{name: "Prefix", query: "chirp*", want: "chirp:*"},
+		{name: "Negation", query: "birds -pigeons", want: "birds & !pigeons"},
+		{name: "Punctuation is dropped", query: "it's (fine) & | !", want: "its & fine"},
+		{name: "Empty", query: "   ", wantErr: true},
+		{name: "Only punctuation", query: `"" & *`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToTSQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToTSQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToTSQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
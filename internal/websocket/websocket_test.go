@@ -0,0 +1,208 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// The example handshake from RFC 6455, section 1.3.
+	got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ==")
+	if want := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("AcceptKey() = %v, want %v", got, want)
+	}
+}
+
+// clientFrame builds a masked frame as a browser would send it.
+func clientFrame(fin bool, opcode int, payload []byte) []byte {
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func pipe(t *testing.T) (*Conn, net.Conn) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return newConn(server, bufio.NewReader(server)), client
+}
+
+func TestReadMessage(t *testing.T) {
+	t.Run("Fragmented text with ping in between", func(t *testing.T) {
+		conn, client := pipe(t)
+		go func() {
+			client.Write(clientFrame(false, TextMessage, []byte("hello ")))
+			client.Write(clientFrame(true, PingMessage, []byte("ping")))
+			client.Write(clientFrame(true, continuationFrame, []byte("world")))
+		}()
+		pong := make(chan []byte)
+		go func() {
+			buf := make([]byte, 6)
+			io.ReadFull(client, buf)
+			pong <- buf
+		}()
+
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		if messageType != TextMessage || string(message) != "hello world" {
+			t.Errorf("ReadMessage() = %v %q, want text %q", messageType, message, "hello world")
+		}
+		if got := <-pong; !bytes.Equal(got, []byte{0x80 | PongMessage, 4, 'p', 'i', 'n', 'g'}) {
+			t.Errorf("pong frame = %v", got)
+		}
+	})
+
+	t.Run("Long message", func(t *testing.T) {
+		conn, client := pipe(t)
+		payload := bytes.Repeat([]byte("a"), 300)
+		go client.Write(clientFrame(true, BinaryMessage, payload))
+
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		if messageType != BinaryMessage || !bytes.Equal(message, payload) {
+			t.Errorf("ReadMessage() = %v, %d bytes", messageType, len(message))
+		}
+	})
+
+	t.Run("Close is acknowledged", func(t *testing.T) {
+		conn, client := pipe(t)
+		go client.Write(clientFrame(true, CloseMessage, []byte{0x03, 0xe8, 'b', 'y', 'e'}))
+		ack := make(chan []byte)
+		go func() {
+			buf := make([]byte, 4)
+			io.ReadFull(client, buf)
+			ack <- buf
+		}()
+
+		_, _, err := conn.ReadMessage()
+		var closeErr *CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != CloseNormalClosure || closeErr.Reason != "bye" {
+			t.Errorf("ReadMessage() error = %v, want close 1000 bye", err)
+		}
+		if got := <-ack; !bytes.Equal(got, []byte{0x80 | CloseMessage, 2, 0x03, 0xe8}) {
+			t.Errorf("close frame = %v", got)
+		}
+	})
+
+	t.Run("Too big", func(t *testing.T) {
+		conn, client := pipe(t)
+		conn.MaxMessageSize = 10
+		go client.Write(clientFrame(true, TextMessage, bytes.Repeat([]byte("a"), 11)))
+		go io.Copy(io.Discard, client)
+
+		_, _, err := conn.ReadMessage()
+		var closeErr *CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != CloseMessageTooBig {
+			t.Errorf("ReadMessage() error = %v, want close %v", err, CloseMessageTooBig)
+		}
+	})
+
+	t.Run("Unmasked frame", func(t *testing.T) {
+		conn, client := pipe(t)
+		go client.Write([]byte{0x80 | TextMessage, 2, 'h', 'i'})
+		go io.Copy(io.Discard, client)
+
+		_, _, err := conn.ReadMessage()
+		var closeErr *CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != CloseProtocolError {
+			t.Errorf("ReadMessage() error = %v, want close %v", err, CloseProtocolError)
+		}
+	})
+}
+
+func TestWriteMessage(t *testing.T) {
+	conn, client := pipe(t)
+	payload := bytes.Repeat([]byte("b"), 200)
+	go conn.WriteMessage(TextMessage, payload)
+
+	frame := make([]byte, 4+len(payload))
+	if _, err := io.ReadFull(client, frame); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	if !bytes.Equal(frame[:4], []byte{0x80 | TextMessage, 126, 0, 200}) {
+		t.Errorf("frame header = %v", frame[:4])
+	}
+	if !bytes.Equal(frame[4:], payload) {
+		t.Error("frame payload does not match")
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	t.Run("Not a websocket request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if _, err := Upgrade(w, req); err != ErrBadHandshake {
+			t.Errorf("Upgrade() error = %v, want %v", err, ErrBadHandshake)
+		}
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Upgrade() status = %v, want %v", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Handshake", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			conn, err := Upgrade(w, req)
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(TextMessage, []byte("hi"))
+			conn.Close()
+		}))
+		defer server.Close()
+
+		client, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		client.Write([]byte("GET / HTTP/1.1\r\nHost: chirpy\r\n" +
+			"Connection: keep-alive, Upgrade\r\nUpgrade: websocket\r\n" +
+			"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+
+		br := bufio.NewReader(client)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("status = %v, want 101", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+			t.Errorf("Sec-WebSocket-Accept = %v", got)
+		}
+		frame := make([]byte, 4)
+		if _, err := io.ReadFull(br, frame); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(frame, []byte{0x80 | TextMessage, 2, 'h', 'i'}) {
+			t.Errorf("first frame = %v", frame)
+		}
+	})
+}
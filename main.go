@@ -1,50 +1,153 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/brendenwelch/chirpy/internal/database"
+	"github.com/brendenwelch/chirpy/internal/mailer"
+	"github.com/brendenwelch/chirpy/internal/media"
+	"github.com/brendenwelch/chirpy/internal/pubsub"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
 	db             *database.Queries
+	sqlDB          *sql.DB
 	fileserverHits atomic.Int32
 	platform       string
 	secret         string
 	polkaKey       string
+	trending       trendingTags
+	chirpStream    *pubsub.Hub[Chirp]
+	media          media.Storage
+	mailer         mailer.Mailer
+	// requireVerifiedEmail bars users from chirping until they verify their
+	// email address.
+	requireVerifiedEmail bool
+	// notificationStream carries every new or regrouped notification.
+	notificationStream *pubsub.Hub[notification]
+	// relationshipStream carries the ID of each user whose follows, blocks or
+	// mutes just changed, so their open streams can reload them.
+	relationshipStream *pubsub.Hub[uuid.UUID]
+	wsSessions         wsRegistry
 }
 
 func main() {
-	cfg := &apiConfig{}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := &apiConfig{
+		chirpStream:        pubsub.NewHub[Chirp](),
+		notificationStream: pubsub.NewHub[notification](),
+		relationshipStream: pubsub.NewHub[uuid.UUID](),
+	}
 	godotenv.Load()
 	cfg.platform = os.Getenv("PLATFORM")
 	cfg.secret = os.Getenv("SECRET")
 	cfg.polkaKey = os.Getenv("POLKA_KEY")
+	cfg.requireVerifiedEmail = os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true"
 
 	db, err := sql.Open("postgres", os.Getenv("DB_URL"))
 	if err != nil {
 		log.Fatalf("failed to open database: %v\n", err)
 	}
 	cfg.db = database.New(db)
+	cfg.sqlDB = db
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	cfg.media, err = media.NewDiskStorage(mediaDir)
+	if err != nil {
+		log.Fatalf("failed to open media storage: %v\n", err)
+	}
+	cfg.mailer, err = newMailer(cfg.platform)
+	if err != nil {
+		log.Fatalf("failed to set up mailer: %v\n", err)
+	}
+	go cfg.runTrendingTags(ctx)
+	go cfg.runScheduler(ctx)
 
 	mux := http.NewServeMux()
 	mux.Handle("/app/", cfg.middlewareMetricsInc(http.StripPrefix("/app/", http.FileServer(http.Dir(".")))))
+	mux.HandleFunc("GET /media/{key}", cfg.handlerServeMedia)
 	mux.HandleFunc("GET /api/healthz", handlerHealth)
 	mux.HandleFunc("POST /api/users", cfg.handlerUsers)
 	mux.HandleFunc("PUT /api/users", cfg.handlerUpdateUser)
 	mux.HandleFunc("POST /api/login", cfg.handlerLogin)
+	mux.HandleFunc("POST /api/login/mfa", cfg.handlerLoginMFA)
+	mux.HandleFunc("POST /api/password-reset/request", cfg.handlerRequestPasswordReset)
+	mux.HandleFunc("POST /api/password-reset/confirm", cfg.handlerConfirmPasswordReset)
+	mux.HandleFunc("POST /api/email-verification/request", cfg.handlerRequestEmailVerification)
+	mux.HandleFunc("POST /api/email-verification/confirm", cfg.handlerConfirmEmailVerification)
 	mux.HandleFunc("POST /api/refresh", cfg.handlerRefresh)
 	mux.HandleFunc("POST /api/revoke", cfg.handlerRevoke)
+	mux.HandleFunc("GET /api/sessions", cfg.handlerGetSessions)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", cfg.handlerRevokeSession)
+	mux.HandleFunc("POST /api/sessions/revoke_others", cfg.handlerRevokeOtherSessions)
+	mux.HandleFunc("POST /api/media", cfg.handlerUploadMedia)
 	mux.HandleFunc("POST /api/chirps", cfg.handlerCreateChirp)
 	mux.HandleFunc("GET /api/chirps", cfg.handlerGetChirps)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.handlerGetChirp)
+	mux.HandleFunc("PATCH /api/chirps/{chirpID}", cfg.handlerEditChirp)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.handlerDeleteChirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", cfg.handlerGetChirpRevisions)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", cfg.handlerGetThread)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", cfg.handlerRechirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/votes", cfg.handlerVote)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", cfg.handlerBookmark)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", cfg.handlerUnbookmark)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/pin", cfg.handlerPin)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", cfg.handlerUnpin)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/likes", cfg.handlerLikeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", cfg.handlerUnlikeChirp)
+	mux.HandleFunc("PATCH /api/users/me/profile", cfg.handlerUpdateProfile)
+	mux.HandleFunc("POST /api/users/me/totp", cfg.handlerEnrollTOTP)
+	mux.HandleFunc("POST /api/users/me/totp/confirm", cfg.handlerConfirmTOTP)
+	mux.HandleFunc("DELETE /api/users/me/totp", cfg.handlerDisableTOTP)
+	mux.HandleFunc("GET /api/users/{handle}", cfg.handlerGetProfile)
+	mux.HandleFunc("GET /api/users/me/mentions", cfg.handlerGetMyMentions)
+	mux.HandleFunc("GET /api/users/me/bookmarks", cfg.handlerGetBookmarks)
+	mux.HandleFunc("POST /api/users/{userID}/follow", cfg.handlerFollow)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", cfg.handlerUnfollow)
+	mux.HandleFunc("GET /api/users/{userID}/followers", cfg.handlerGetFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", cfg.handlerGetFollowing)
+	mux.HandleFunc("POST /api/users/{userID}/block", cfg.handlerBlock)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", cfg.handlerUnblock)
+	mux.HandleFunc("POST /api/users/{userID}/mute", cfg.handlerMute)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", cfg.handlerUnmute)
+	mux.HandleFunc("GET /api/users/me/blocks", cfg.handlerGetBlocks)
+	mux.HandleFunc("GET /api/users/me/mutes", cfg.handlerGetMutes)
+	mux.HandleFunc("GET /api/timeline", cfg.handlerTimeline)
+	mux.HandleFunc("GET /api/stream/chirps", cfg.handlerStreamChirps)
+	mux.HandleFunc("GET /api/ws", cfg.handlerWebSocket)
+	mux.HandleFunc("POST /api/conversations", cfg.handlerCreateConversation)
+	mux.HandleFunc("GET /api/conversations", cfg.handlerGetConversations)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", cfg.handlerCreateMessage)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", cfg.handlerGetMessages)
+	mux.HandleFunc("POST /api/drafts", cfg.handlerCreateDraft)
+	mux.HandleFunc("GET /api/drafts", cfg.handlerGetDrafts)
+	mux.HandleFunc("GET /api/drafts/{draftID}", cfg.handlerGetDraft)
+	mux.HandleFunc("PUT /api/drafts/{draftID}", cfg.handlerUpdateDraft)
+	mux.HandleFunc("DELETE /api/drafts/{draftID}", cfg.handlerDeleteDraft)
+	mux.HandleFunc("GET /api/scheduled/chirps", cfg.handlerGetScheduledChirps)
+	mux.HandleFunc("DELETE /api/scheduled/chirps/{scheduledID}", cfg.handlerCancelScheduledChirp)
+	mux.HandleFunc("GET /api/notifications", cfg.handlerGetNotifications)
+	mux.HandleFunc("POST /api/notifications/read", cfg.handlerReadNotifications)
+	mux.HandleFunc("GET /api/search/chirps", cfg.handlerSearchChirps)
+	mux.HandleFunc("GET /api/tags/trending", cfg.handlerTrendingTags)
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", cfg.handlerGetTagChirps)
 	mux.HandleFunc("POST /api/polka/webhooks", cfg.handlerUpgradeUser)
 	mux.HandleFunc("GET /admin/metrics", cfg.handlerMetrics)
 	mux.HandleFunc("POST /admin/reset", cfg.handlerReset)
@@ -53,7 +156,48 @@ func main() {
 		Addr:    ":8080",
 		Handler: mux,
 	}
-	if err := server.ListenAndServe(); err != nil {
+	// Shutdown waits for handlers to return, so end the streams first.
+	server.RegisterOnShutdown(cfg.chirpStream.Close)
+	server.RegisterOnShutdown(cfg.notificationStream.Close)
+	server.RegisterOnShutdown(cfg.relationshipStream.Close)
+	server.RegisterOnShutdown(cfg.wsSessions.Close)
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to shut down cleanly: %v", err)
+		}
+	}()
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
 		log.Fatalf("Server closed: %v", err)
 	}
+	<-shutdownDone
+}
+
+// newMailer sends mail over SMTP when SMTP_ADDR is set. On the dev platform
+// mail may instead be written to MAIL_LOG_FILE, or stderr. Anywhere else a
+// missing SMTP_ADDR is an error, so reset links never end up in a log.
+func newMailer(platform string) (mailer.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "chirpy@localhost"
+	}
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		return mailer.NewSMTPMailer(addr, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+	}
+	if platform != "dev" {
+		return nil, errors.New("SMTP_ADDR must be set outside the dev platform")
+	}
+	logFile := os.Getenv("MAIL_LOG_FILE")
+	if logFile == "" {
+		return mailer.NewLogMailer(os.Stderr, from), nil
+	}
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return mailer.NewLogMailer(f, from), nil
 }
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// page holds the keyset pagination parameters shared by every list endpoint.
+// Results are ordered by (created_at, id), and the cursor points at the last
+// row of the previous page.
+type page struct {
+	limit           int32
+	cursorCreatedAt sql.NullTime
+	cursorID        uuid.NullUUID
+}
+
+// rankedPage is a page of results ordered by relevance first, such as search
+// results, so the cursor carries the rank of the last row too.
+type rankedPage struct {
+	page
+	cursorRank sql.NullFloat64
+}
+
+func parsePage(query url.Values) (page, error) {
+	limit, err := parseLimit(query)
+	if err != nil {
+		return page{}, err
+	}
+	p := page{limit: limit}
+	if cursorString := query.Get("cursor"); cursorString != "" {
+		createdAt, id, err := decodeCursor(cursorString)
+		if err != nil {
+			return page{}, err
+		}
+		p.cursorCreatedAt = sql.NullTime{Time: createdAt, Valid: true}
+		p.cursorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	return p, nil
+}
+
+func parseRankedPage(query url.Values) (rankedPage, error) {
+	limit, err := parseLimit(query)
+	if err != nil {
+		return rankedPage{}, err
+	}
+	p := rankedPage{page: page{limit: limit}}
+	if cursorString := query.Get("cursor"); cursorString != "" {
+		fields, err := decodeCursorFields(cursorString, 3)
+		if err != nil {
+			return rankedPage{}, err
+		}
+		rank, err := strconv.ParseFloat(fields[0], 32)
+		if err != nil {
+			return rankedPage{}, errors.New("invalid cursor")
+		}
+		createdAt, id, err := parseCursorFields(fields[1], fields[2])
+		if err != nil {
+			return rankedPage{}, err
+		}
+		p.cursorRank = sql.NullFloat64{Float64: rank, Valid: true}
+		p.cursorCreatedAt = sql.NullTime{Time: createdAt, Valid: true}
+		p.cursorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	return p, nil
+}
+
+func parseLimit(query url.Values) (int32, error) {
+	limitString := query.Get("limit")
+	if limitString == "" {
+		return defaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(limitString)
+	if err != nil || limit < 1 {
+		return 0, errors.New("invalid limit")
+	}
+	return int32(min(limit, maxPageLimit)), nil
+}
+
+// fetchLimit asks the database for one row more than the page size, so we can
+// tell whether another page exists without a separate count query.
+func (p page) fetchLimit() int32 {
+	return p.limit + 1
+}
+
+// trimPage cuts items down to the page size and reports whether more remain.
+func trimPage[T any](items []T, p page) ([]T, bool) {
+	if int32(len(items)) <= p.limit {
+		return items, false
+	}
+	return items[:p.limit], true
+}
+
+// setNextLink points the client at the next page with a Link header, for
+// endpoints that predate pagination and so still respond with a bare array.
+func setNextLink(w http.ResponseWriter, req *http.Request, cursor string) {
+	next := *req.URL
+	query := next.Query()
+	query.Set("cursor", cursor)
+	next.RawQuery = query.Encode()
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+}
+
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	return encodeCursorFields(createdAt.Format(time.RFC3339Nano), id.String())
+}
+
+func encodeRankedCursor(rank float32, createdAt time.Time, id uuid.UUID) string {
+	return encodeCursorFields(
+		strconv.FormatFloat(float64(rank), 'g', -1, 32),
+		createdAt.Format(time.RFC3339Nano),
+		id.String(),
+	)
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	fields, err := decodeCursorFields(cursor, 2)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	return parseCursorFields(fields[0], fields[1])
+}
+
+func encodeCursorFields(fields ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(fields, "|")))
+}
+
+func decodeCursorFields(cursor string, n int) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	fields := strings.Split(string(raw), "|")
+	if len(fields) != n {
+		return nil, errors.New("invalid cursor")
+	}
+	return fields, nil
+}
+
+func parseCursorFields(createdAtString, idString string) (time.Time, uuid.UUID, error) {
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtString)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errors.New("invalid cursor")
+	}
+	id, err := uuid.Parse(idString)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errors.New("invalid cursor")
+	}
+	return createdAt, id, nil
+}
@@ -0,0 +1,58 @@
+-- name: CreateBlock :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+	)
+ON CONFLICT (blocker_id, blocked_id) DO NOTHING;
+
+-- name: DeleteBlock :exec
+DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: ListBlocks :many
+SELECT * FROM blocks
+WHERE blocker_id = sqlc.arg('user_id')
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, blocked_id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, blocked_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: BlockExists :one
+SELECT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocker_id = sqlc.arg('user_a_id') AND blocked_id = sqlc.arg('user_b_id'))
+	OR (blocker_id = sqlc.arg('user_b_id') AND blocked_id = sqlc.arg('user_a_id'))
+);
+
+-- name: ListBlockedUserIDs :many
+SELECT blocked_id AS user_id FROM blocks WHERE blocker_id = sqlc.arg('user_id')
+UNION
+SELECT blocker_id AS user_id FROM blocks WHERE blocked_id = sqlc.arg('user_id');
+
+-- name: CreateMute :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+	)
+ON CONFLICT (muter_id, muted_id) DO NOTHING;
+
+-- name: DeleteMute :exec
+DELETE FROM mutes WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: ListMutes :many
+SELECT * FROM mutes
+WHERE muter_id = sqlc.arg('user_id')
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, muted_id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, muted_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListMutedUserIDs :many
+SELECT muted_id FROM mutes WHERE muter_id = $1;
@@ -0,0 +1,75 @@
+-- name: CreateBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+	)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: ListBookmarkedChirps :many
+SELECT sqlc.embed(chirps), bookmarks.created_at AS bookmarked_at FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = sqlc.arg('user_id')
+AND chirps.deleted_at IS NULL
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('user_id'))
+	OR (blocks.blocker_id = sqlc.arg('user_id') AND blocks.blocked_id = chirps.user_id)
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = sqlc.arg('user_id')
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg('user_id') AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.arg('user_id')
+	))
+)
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (bookmarks.created_at, bookmarks.chirp_id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY bookmarks.created_at DESC, bookmarks.chirp_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CreatePin :exec
+INSERT INTO pins (user_id, chirp_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+	)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: DeletePin :exec
+DELETE FROM pins WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: ListPinnedChirpIDs :many
+SELECT chirp_id FROM pins WHERE user_id = $1;
+
+-- name: ListPinnedChirps :many
+SELECT chirps.* FROM pins
+JOIN chirps ON chirps.id = pins.chirp_id
+WHERE pins.user_id = sqlc.arg('user_id')
+AND chirps.deleted_at IS NULL
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+	OR (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.narg('viewer_id')::uuid
+	))
+)
+ORDER BY pins.created_at DESC;
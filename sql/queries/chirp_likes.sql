@@ -0,0 +1,20 @@
+-- name: CreateChirpLike :execrows
+INSERT INTO chirp_likes (chirp_id, user_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+	)
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: DeleteChirpLike :exec
+DELETE FROM chirp_likes WHERE chirp_id = $1 AND user_id = $2;
+
+-- name: CountChirpLikes :many
+SELECT chirp_id, count(*) AS like_count FROM chirp_likes
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY chirp_id;
+
+-- name: ListLikedChirpIDs :many
+SELECT chirp_id FROM chirp_likes
+WHERE user_id = sqlc.arg('user_id') AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
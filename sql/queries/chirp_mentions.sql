@@ -0,0 +1,50 @@
+-- name: CreateChirpMentions :many
+INSERT INTO chirp_mentions (chirp_id, user_id, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, id, NOW() FROM users
+WHERE handle = ANY(sqlc.arg('handles')::text[])
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocker_id = users.id AND blocked_id = sqlc.arg('author_id'))
+	OR (blocker_id = sqlc.arg('author_id') AND blocked_id = users.id)
+)
+ON CONFLICT (chirp_id, user_id) DO NOTHING
+RETURNING user_id;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions WHERE chirp_id = $1;
+
+-- name: ListChirpMentions :many
+SELECT chirp_mentions.chirp_id, users.id AS user_id, users.handle FROM chirp_mentions
+JOIN users ON users.id = chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY users.handle ASC;
+
+-- name: ListMentioningChirps :many
+SELECT chirps.* FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = sqlc.arg('user_id')
+AND chirps.deleted_at IS NULL
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('user_id'))
+	OR (blocks.blocker_id = sqlc.arg('user_id') AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = sqlc.arg('user_id') AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = sqlc.arg('user_id')
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg('user_id') AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.arg('user_id')
+	))
+)
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
@@ -1,25 +1,192 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_id, thread_id, original_id, visibility)
 VALUES (
 	gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3,
+	$4,
+	$5,
+	$6
 	)
 RETURNING *;
 
--- name: GetAllChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, original_id, is_rechirp)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	NOW(),
+	'',
+	$1,
+	$2,
+	true
+	)
+RETURNING *;
 
--- name: GetAllChirpsByUser :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+	OR (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = sqlc.narg('viewer_id')::uuid AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.narg('viewer_id')::uuid
+	))
+)
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+	OR (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = sqlc.narg('viewer_id')::uuid AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.narg('viewer_id')::uuid
+	))
+)
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetChirp :one
 SELECT * FROM chirps WHERE id = $1;
 
+-- name: GetThread :many
+SELECT * FROM chirps
+WHERE (id = sqlc.arg('id') OR thread_id = sqlc.arg('id'))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+	OR (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = sqlc.narg('viewer_id')::uuid AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.narg('viewer_id')::uuid
+	))
+)
+ORDER BY created_at ASC, id ASC;
+
+-- name: CanViewChirp :one
+SELECT EXISTS (
+	SELECT 1 FROM chirps
+	WHERE id = sqlc.arg('id')
+	AND (
+		chirps.visibility = 'public'
+		OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+		OR (chirps.visibility = 'followers' AND EXISTS (
+			SELECT 1 FROM follows WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id
+		))
+		OR (chirps.visibility = 'mentioned' AND EXISTS (
+			SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.narg('viewer_id')::uuid
+		))
+	)
+);
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: CountChirpReferences :one
+SELECT count(*) FROM chirps
+WHERE parent_id = sqlc.arg('id') OR original_id = sqlc.arg('id');
+
+-- name: EditChirp :one
+WITH revision AS (
+	INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+	SELECT gen_random_uuid(), id, body, updated_at, NOW()
+	FROM chirps WHERE id = sqlc.arg('id')
+)
+UPDATE chirps
+SET updated_at = NOW(), body = sqlc.arg('body')
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: TombstoneChirp :exec
+WITH revisions AS (
+	DELETE FROM chirp_revisions WHERE chirp_id = sqlc.arg('id')
+), bookmarks AS (
+	DELETE FROM bookmarks WHERE chirp_id = sqlc.arg('id')
+), pins AS (
+	DELETE FROM pins WHERE chirp_id = sqlc.arg('id')
+)
+UPDATE chirps
+SET updated_at = NOW(), body = '', deleted_at = NOW()
+WHERE id = sqlc.arg('id');
+
 -- name: DeleteChirp :exec
 DELETE FROM chirps WHERE id = $1;
 
 -- name: ResetChirps :exec
 DELETE FROM chirps;
+
+-- name: ListTimeline :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg('user_id'))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('user_id'))
+	OR (blocks.blocker_id = sqlc.arg('user_id') AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = sqlc.arg('user_id') AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = sqlc.arg('user_id')
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = sqlc.arg('user_id') AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.arg('user_id')
+	))
+)
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
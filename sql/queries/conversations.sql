@@ -0,0 +1,48 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, user_a_id, user_b_id)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	NOW(),
+	$1,
+	$2
+	)
+ON CONFLICT (user_a_id, user_b_id) DO UPDATE SET user_a_id = EXCLUDED.user_a_id
+RETURNING *;
+
+-- name: GetConversation :one
+SELECT * FROM conversations WHERE id = $1;
+
+-- name: ListConversations :many
+SELECT * FROM conversations
+WHERE (user_a_id = sqlc.arg('user_id') OR user_b_id = sqlc.arg('user_id'))
+AND (
+	sqlc.narg('cursor_updated_at')::timestamp IS NULL
+	OR (updated_at, id) < (sqlc.narg('cursor_updated_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY updated_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CreateMessage :one
+WITH bumped AS (
+	UPDATE conversations SET updated_at = NOW() WHERE id = sqlc.arg('conversation_id')
+)
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	sqlc.arg('conversation_id'),
+	sqlc.arg('sender_id'),
+	sqlc.arg('body')
+	)
+RETURNING *;
+
+-- name: ListMessages :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg('conversation_id')
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,19 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (token_hash, user_id, email, created_at, expires_at, used_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	NOW(),
+	NOW() + INTERVAL '24 hours',
+	NULL
+	);
+
+-- name: UseEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteEmailVerificationTokens :exec
+DELETE FROM email_verification_tokens WHERE user_id = $1;
@@ -0,0 +1,39 @@
+-- name: CreateFollow :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+	)
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: DeleteFollow :exec
+DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: ListFollowers :many
+SELECT * FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, follower_id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListFollowing :many
+SELECT * FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, followee_id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListFolloweeIDs :many
+SELECT followee_id FROM follows WHERE follower_id = $1;
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg('user_a_id') AND followee_id = sqlc.arg('user_b_id'))
+OR (follower_id = sqlc.arg('user_b_id') AND followee_id = sqlc.arg('user_a_id'));
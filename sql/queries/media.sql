@@ -0,0 +1,41 @@
+-- name: CreateMediaFile :one
+INSERT INTO media_files (id, created_at, user_id, content_type, width, height, size, storage_key, thumbnail_key)
+VALUES (
+	$1,
+	NOW(),
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	$8
+	)
+RETURNING *;
+
+-- name: CountAttachableMediaFiles :one
+SELECT count(*) FROM media_files
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+AND user_id = sqlc.arg('user_id')
+AND NOT EXISTS (SELECT 1 FROM chirp_attachments WHERE chirp_attachments.media_id = media_files.id);
+
+-- name: CreateChirpAttachments :exec
+INSERT INTO chirp_attachments (chirp_id, media_id, position)
+SELECT sqlc.arg('chirp_id')::uuid, ids.id, ids.position
+FROM unnest(sqlc.arg('media_ids')::uuid[]) WITH ORDINALITY AS ids(id, position);
+
+-- name: ListChirpAttachments :many
+SELECT chirp_attachments.chirp_id, sqlc.embed(media_files) FROM chirp_attachments
+JOIN media_files ON media_files.id = chirp_attachments.media_id
+WHERE chirp_attachments.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_attachments.position ASC;
+
+-- name: GetMediaFileByKey :one
+SELECT media_files.user_id, chirp_attachments.chirp_id FROM media_files
+LEFT JOIN chirp_attachments ON chirp_attachments.media_id = media_files.id
+WHERE media_files.storage_key = sqlc.arg('key') OR media_files.thumbnail_key = sqlc.arg('key');
+
+-- name: DeleteChirpMediaFiles :many
+DELETE FROM media_files
+WHERE id IN (SELECT media_id FROM chirp_attachments WHERE chirp_id = $1)
+RETURNING storage_key, thumbnail_key;
@@ -0,0 +1,39 @@
+-- name: UpsertNotification :one
+INSERT INTO notifications (id, created_at, updated_at, user_id, kind, chirp_id, actor_id, count, read_at)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	NOW(),
+	$1,
+	$2,
+	$3,
+	$4,
+	1,
+	NULL
+	)
+ON CONFLICT (user_id, kind, COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'::uuid))
+	WHERE read_at IS NULL
+DO UPDATE SET updated_at = NOW(), actor_id = EXCLUDED.actor_id, count = notifications.count + 1
+RETURNING *;
+
+-- name: ListNotifications :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+AND (NOT sqlc.arg('unread_only')::boolean OR read_at IS NULL)
+AND (sqlc.narg('since')::timestamp IS NULL OR updated_at > sqlc.narg('since'))
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CountUnreadNotifications :one
+SELECT count(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+AND read_at IS NULL
+AND (COALESCE(cardinality(sqlc.arg('ids')::uuid[]), 0) = 0 OR id = ANY(sqlc.arg('ids')::uuid[]));
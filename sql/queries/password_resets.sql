@@ -0,0 +1,18 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (token_hash, user_id, created_at, expires_at, used_at)
+VALUES (
+	$1,
+	$2,
+	NOW(),
+	NOW() + INTERVAL '1 hour',
+	NULL
+	);
+
+-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeletePasswordResetTokens :exec
+DELETE FROM password_reset_tokens WHERE user_id = $1;
@@ -0,0 +1,45 @@
+-- name: CreatePoll :one
+INSERT INTO polls (id, created_at, chirp_id, closes_at)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	$1,
+	$2
+	)
+RETURNING *;
+
+-- name: CreatePollOptions :exec
+INSERT INTO poll_options (id, poll_id, position, text)
+SELECT gen_random_uuid(), sqlc.arg('poll_id')::uuid, options.position, options.text
+FROM unnest(sqlc.arg('texts')::text[]) WITH ORDINALITY AS options(text, position);
+
+-- name: GetPollByChirpID :one
+SELECT * FROM polls WHERE chirp_id = $1;
+
+-- name: ListPollOptions :many
+SELECT * FROM poll_options WHERE poll_id = $1 ORDER BY position ASC;
+
+-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (poll_id, user_id, option_id, created_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	NOW()
+	)
+ON CONFLICT (poll_id, user_id) DO NOTHING;
+
+-- name: ListChirpPollTallies :many
+SELECT polls.chirp_id, polls.id AS poll_id, polls.closes_at, poll_options.id AS option_id, poll_options.text,
+	count(poll_votes.user_id) AS votes
+FROM polls
+JOIN poll_options ON poll_options.poll_id = polls.id
+LEFT JOIN poll_votes ON poll_votes.option_id = poll_options.id
+WHERE polls.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY polls.id, poll_options.id
+ORDER BY poll_options.position ASC;
+
+-- name: ListPollVotesByUser :many
+SELECT poll_id, option_id FROM poll_votes
+WHERE user_id = sqlc.arg('user_id')
+AND poll_id = ANY(sqlc.arg('poll_ids')::uuid[]);
@@ -1,12 +1,18 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, rotated_at, user_agent, ip, last_used_at)
 VALUES (
 	$1,
 	NOW(),
 	NOW(),
 	$2,
 	NOW() + INTERVAL '60 days',
-	NULL
+	NULL,
+	$3,
+	$4,
+	NULL,
+	$5,
+	$6,
+	NOW()
 	)
 RETURNING *;
 
@@ -17,3 +23,38 @@ SELECT * FROM refresh_tokens WHERE token = $1;
 UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
 WHERE token = $1;
+
+-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW(), rotated_at = NOW()
+WHERE token = $1 AND revoked_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: ListSessions :many
+SELECT family_id, user_agent, ip, last_used_at, expires_at, (
+	SELECT min(created_at) FROM refresh_tokens AS family
+	WHERE family.family_id = refresh_tokens.family_id
+)::timestamp AS signed_in_at
+FROM refresh_tokens
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+ORDER BY last_used_at DESC, family_id DESC;
+
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeOtherSessions :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = sqlc.arg('user_id') AND family_id <> sqlc.arg('family_id') AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
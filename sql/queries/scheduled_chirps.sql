@@ -0,0 +1,41 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (id, created_at, user_id, body, publish_at, published_at, chirp_id, visibility)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	$1,
+	$2,
+	$3,
+	NULL,
+	NULL,
+	$4
+	)
+RETURNING *;
+
+-- name: ListScheduledChirps :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = sqlc.arg('user_id')
+AND published_at IS NULL
+AND (
+	sqlc.narg('cursor_publish_at')::timestamp IS NULL
+	OR (publish_at, id) > (sqlc.narg('cursor_publish_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY publish_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: CancelScheduledChirp :execrows
+DELETE FROM scheduled_chirps
+WHERE id = $1 AND user_id = $2 AND published_at IS NULL;
+
+-- name: ClaimDueScheduledChirp :one
+SELECT * FROM scheduled_chirps
+WHERE published_at IS NULL
+AND publish_at <= NOW()
+ORDER BY publish_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkScheduledChirpPublished :exec
+UPDATE scheduled_chirps
+SET published_at = NOW(), chirp_id = $2
+WHERE id = $1;
@@ -0,0 +1,33 @@
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirps), ts_rank(to_tsvector('english', chirps.body), query)::real AS rank
+FROM chirps, to_tsquery('english', sqlc.arg('query')) AS query
+WHERE chirps.deleted_at IS NULL
+AND to_tsvector('english', chirps.body) @@ query
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id'))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+	OR (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = sqlc.narg('viewer_id')::uuid AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.narg('viewer_id')::uuid
+	))
+)
+AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since'))
+AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until'))
+AND (
+	sqlc.narg('cursor_rank')::real IS NULL
+	OR (ts_rank(to_tsvector('english', chirps.body), query), chirps.created_at, chirps.id)
+		< (sqlc.narg('cursor_rank'), sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,58 @@
+-- name: CreateTags :exec
+INSERT INTO tags (id, created_at, name)
+SELECT gen_random_uuid(), NOW(), unnest(sqlc.arg('names')::text[])
+ON CONFLICT (name) DO NOTHING;
+
+-- name: CreateChirpTags :exec
+INSERT INTO chirp_tags (chirp_id, tag_id, created_at)
+SELECT chirps.id, tags.id, chirps.created_at FROM tags
+JOIN chirps ON chirps.id = sqlc.arg('chirp_id')::uuid
+WHERE tags.name = ANY(sqlc.arg('names')::text[])
+ON CONFLICT (chirp_id, tag_id) DO NOTHING;
+
+-- name: DeleteOtherChirpTags :exec
+DELETE FROM chirp_tags
+WHERE chirp_id = sqlc.arg('chirp_id')
+AND tag_id NOT IN (SELECT id FROM tags WHERE name = ANY(sqlc.arg('names')::text[]));
+
+-- name: ListChirpsByTag :many
+SELECT chirps.* FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+JOIN tags ON tags.id = chirp_tags.tag_id
+WHERE tags.name = sqlc.arg('name')
+AND chirps.deleted_at IS NULL
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+	OR (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes WHERE mutes.muter_id = sqlc.narg('viewer_id')::uuid AND mutes.muted_id = chirps.user_id
+)
+AND (
+	chirps.visibility = 'public'
+	OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+	OR (chirps.visibility = 'followers' AND EXISTS (
+		SELECT 1 FROM follows WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id
+	))
+	OR (chirps.visibility = 'mentioned' AND EXISTS (
+		SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.narg('viewer_id')::uuid
+	))
+)
+AND (
+	sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListTrendingTags :many
+SELECT tags.name, count(*) AS uses FROM chirp_tags
+JOIN tags ON tags.id = chirp_tags.tag_id
+JOIN chirps ON chirps.id = chirp_tags.chirp_id
+WHERE chirp_tags.created_at >= NOW() - sqlc.arg('window_seconds')::int * INTERVAL '1 second'
+AND chirps.deleted_at IS NULL
+AND chirps.visibility = 'public'
+GROUP BY tags.name
+ORDER BY uses DESC, tags.name ASC
+LIMIT sqlc.arg('limit');
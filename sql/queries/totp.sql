@@ -0,0 +1,59 @@
+-- name: UpsertTOTPSecret :one
+INSERT INTO totp_secrets (user_id, created_at, secret)
+VALUES (
+	$1,
+	NOW(),
+	$2
+	)
+ON CONFLICT (user_id) DO UPDATE
+SET created_at = NOW(), secret = EXCLUDED.secret, last_used_step = 0, failed_attempts = 0, last_failed_at = NULL
+WHERE totp_secrets.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetTOTPSecret :one
+SELECT * FROM totp_secrets WHERE user_id = $1;
+
+-- name: ConfirmTOTPSecret :execrows
+UPDATE totp_secrets
+SET confirmed_at = NOW(), last_used_step = $2, failed_attempts = 0
+WHERE user_id = $1 AND confirmed_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE totp_secrets
+SET last_used_step = sqlc.arg('step'), failed_attempts = 0
+WHERE user_id = sqlc.arg('user_id') AND last_used_step < sqlc.arg('step');
+
+-- name: ClaimTOTPAttempt :one
+UPDATE totp_secrets
+SET failed_attempts = CASE
+		WHEN last_failed_at < NOW() - sqlc.arg('window_seconds')::int * INTERVAL '1 second' THEN 1
+		ELSE failed_attempts + 1
+	END,
+	last_failed_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+AND NOT (
+	failed_attempts >= sqlc.arg('max_attempts')::int
+	AND last_failed_at >= NOW() - sqlc.arg('window_seconds')::int * INTERVAL '1 second'
+)
+RETURNING failed_attempts;
+
+-- name: ResetTOTPFailures :exec
+UPDATE totp_secrets SET failed_attempts = 0 WHERE user_id = $1;
+
+-- name: DeleteTOTPSecret :exec
+WITH codes AS (
+	DELETE FROM recovery_codes WHERE user_id = sqlc.arg('user_id')
+)
+DELETE FROM totp_secrets WHERE user_id = sqlc.arg('user_id');
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (user_id, code_hash, created_at, used_at)
+SELECT sqlc.arg('user_id')::uuid, unnest(sqlc.arg('code_hashes')::text[]), NOW(), NULL;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
@@ -1,20 +1,30 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
 	gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3
 	)
 RETURNING *;
 
+-- name: GetUser :one
+SELECT * FROM users WHERE id = $1;
+
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email=$1;
 
 -- name: UpdateUser :one
 UPDATE users
-SET updated_at = NOW(), email = $2, hashed_password = $3
+SET updated_at = NOW(), hashed_password = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserHandle :one
+UPDATE users
+SET updated_at = NOW(), handle = $2
 WHERE id = $1
 RETURNING *;
 
@@ -26,3 +36,30 @@ RETURNING *;
 
 -- name: ResetUsers :exec
 DELETE FROM users;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users WHERE handle = $1;
+
+-- name: UpdateProfile :one
+UPDATE users
+SET updated_at = NOW(), handle = $2, display_name = $3, bio = $4, avatar_url = $5
+WHERE id = $1
+RETURNING *;
+
+-- name: ListAuthors :many
+SELECT id, handle, display_name, avatar_url FROM users
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: LockUser :exec
+SELECT id FROM users WHERE id = $1 FOR UPDATE;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET updated_at = NOW(), hashed_password = $2
+WHERE id = $1;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET updated_at = NOW(), email = $2, email_verified_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE chirps
+	ADD COLUMN parent_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+	ADD COLUMN thread_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+	ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX chirps_parent_id_idx ON chirps (parent_id);
+CREATE INDEX chirps_thread_id_idx ON chirps (thread_id, created_at, id);
+
+-- +goose Down
+ALTER TABLE chirps
+	DROP COLUMN deleted_at,
+	DROP COLUMN thread_id,
+	DROP COLUMN parent_id;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE chirps
+	ADD COLUMN original_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+	ADD COLUMN is_rechirp BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX chirps_original_id_idx ON chirps (original_id);
+-- A rechirp shares an original with no body of its own; a quote has both.
+-- Each user can rechirp a given chirp only once.
+CREATE UNIQUE INDEX chirps_rechirp_unique_idx ON chirps (user_id, original_id)
+	WHERE is_rechirp AND deleted_at IS NULL;
+
+-- +goose Down
+ALTER TABLE chirps
+	DROP COLUMN is_rechirp,
+	DROP COLUMN original_id;
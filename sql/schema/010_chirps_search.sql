@@ -0,0 +1,7 @@
+-- +goose Up
+-- Search matches the same expression as this index, so the tsvector never
+-- has to be stored on the chirp row itself.
+CREATE INDEX chirps_search_idx ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX chirps_search_idx;
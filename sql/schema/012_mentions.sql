@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users
+	ADD COLUMN handle TEXT;
+UPDATE users SET handle = 'user_' || substr(replace(id::text, '-', ''), 1, 10);
+ALTER TABLE users
+	ALTER COLUMN handle SET NOT NULL,
+	ADD CONSTRAINT users_handle_key UNIQUE (handle);
+
+CREATE TABLE chirp_mentions(
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (chirp_id, user_id)
+);
+CREATE INDEX chirp_mentions_user_id_created_at_idx ON chirp_mentions (user_id, created_at);
+
+-- +goose Down
+DROP TABLE chirp_mentions;
+ALTER TABLE users
+	DROP COLUMN handle;
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE notifications(
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+	actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	count INTEGER NOT NULL DEFAULT 1,
+	read_at TIMESTAMP
+);
+CREATE INDEX notifications_user_id_created_at_idx ON notifications (user_id, created_at, id);
+-- Unread notifications of the same kind about the same chirp are grouped into
+-- one entry, so a burst of likes shows up once.
+CREATE UNIQUE INDEX notifications_unread_group_idx
+	ON notifications (user_id, kind, COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'::uuid))
+	WHERE read_at IS NULL;
+
+-- Notifications from a user that either side has blocked are dropped.
+CREATE TABLE blocks(
+	blocker_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	blocked_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (blocker_id, blocked_id),
+	CHECK (blocker_id <> blocked_id)
+);
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id);
+
+-- +goose Down
+DROP TABLE blocks;
+DROP TABLE notifications;
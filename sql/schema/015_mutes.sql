@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE mutes(
+	muter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	muted_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (muter_id, muted_id),
+	CHECK (muter_id <> muted_id)
+);
+
+-- +goose Down
+DROP TABLE mutes;
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE media_files(
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	content_type TEXT NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL,
+	size INTEGER NOT NULL,
+	storage_key TEXT NOT NULL,
+	thumbnail_key TEXT NOT NULL
+);
+CREATE INDEX media_files_user_id_idx ON media_files (user_id);
+-- Media is served by key, so each key names exactly one file.
+CREATE UNIQUE INDEX media_files_storage_key_idx ON media_files (storage_key);
+CREATE UNIQUE INDEX media_files_thumbnail_key_idx ON media_files (thumbnail_key);
+
+-- Each upload can be attached to one chirp.
+CREATE TABLE chirp_attachments(
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	media_id UUID NOT NULL UNIQUE REFERENCES media_files(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (chirp_id, media_id)
+);
+
+-- +goose Down
+DROP TABLE chirp_attachments;
+DROP TABLE media_files;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE drafts(
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	body TEXT NOT NULL
+);
+CREATE INDEX drafts_user_id_created_at_idx ON drafts (user_id, created_at, id);
+
+-- A scheduled chirp is published by setting published_at and chirp_id in the
+-- same transaction that creates the chirp, so it goes out exactly once.
+CREATE TABLE scheduled_chirps(
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	body TEXT NOT NULL,
+	publish_at TIMESTAMP NOT NULL,
+	published_at TIMESTAMP,
+	chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL
+);
+CREATE INDEX scheduled_chirps_due_idx ON scheduled_chirps (publish_at) WHERE published_at IS NULL;
+CREATE INDEX scheduled_chirps_user_id_idx ON scheduled_chirps (user_id, publish_at, id);
+
+-- +goose Down
+DROP TABLE scheduled_chirps;
+DROP TABLE drafts;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- public chirps can be read by anyone, followers chirps only by the author's
+-- followers and mentioned chirps only by the users they mention. Authors can
+-- always read their own.
+ALTER TABLE chirps
+	ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+		CHECK (visibility IN ('public', 'followers', 'mentioned'));
+ALTER TABLE scheduled_chirps
+	ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+		CHECK (visibility IN ('public', 'followers', 'mentioned'));
+
+-- +goose Down
+ALTER TABLE scheduled_chirps
+	DROP COLUMN visibility;
+ALTER TABLE chirps
+	DROP COLUMN visibility;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Each login starts a family of refresh tokens. Refreshing replaces the
+-- token with a child in the same family, so reuse of an old one can revoke
+-- every token descended from the same login.
+ALTER TABLE refresh_tokens
+	ADD COLUMN family_id UUID,
+	ADD COLUMN parent_token TEXT REFERENCES refresh_tokens(token) ON DELETE SET NULL,
+	ADD COLUMN rotated_at TIMESTAMP;
+UPDATE refresh_tokens SET family_id = gen_random_uuid();
+ALTER TABLE refresh_tokens
+	ALTER COLUMN family_id SET NOT NULL;
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+	DROP COLUMN rotated_at,
+	DROP COLUMN parent_token,
+	DROP COLUMN family_id;
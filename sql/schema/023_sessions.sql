@@ -0,0 +1,18 @@
+-- +goose Up
+-- A session is a refresh token family. Its live token records the device
+-- that last used it.
+ALTER TABLE refresh_tokens
+	ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+	ADD COLUMN ip TEXT NOT NULL DEFAULT '',
+	ADD COLUMN last_used_at TIMESTAMP;
+UPDATE refresh_tokens SET last_used_at = created_at;
+ALTER TABLE refresh_tokens
+	ALTER COLUMN last_used_at SET NOT NULL;
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_user_id_idx;
+ALTER TABLE refresh_tokens
+	DROP COLUMN last_used_at,
+	DROP COLUMN ip,
+	DROP COLUMN user_agent;
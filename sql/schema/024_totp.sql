@@ -0,0 +1,25 @@
+-- +goose Up
+-- A user has two-factor authentication on once their secret is confirmed.
+-- last_used_step stops a code being used twice, and failed_attempts limits
+-- guessing.
+CREATE TABLE totp_secrets(
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	secret TEXT NOT NULL,
+	confirmed_at TIMESTAMP,
+	last_used_step BIGINT NOT NULL DEFAULT 0,
+	failed_attempts INTEGER NOT NULL DEFAULT 0,
+	last_failed_at TIMESTAMP
+);
+
+CREATE TABLE recovery_codes(
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	code_hash TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	PRIMARY KEY (user_id, code_hash)
+);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+DROP TABLE totp_secrets;
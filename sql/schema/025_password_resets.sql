@@ -0,0 +1,14 @@
+-- +goose Up
+-- Only a hash of each reset token is kept, so a leaked table can't be used
+-- to take over accounts.
+CREATE TABLE password_reset_tokens(
+	token_hash TEXT PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP
+);
+CREATE INDEX password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
+
+-- +goose Down
+DROP TABLE password_reset_tokens;
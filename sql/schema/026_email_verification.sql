@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+-- Accounts from before verification keep signing in as they did.
+UPDATE users SET email_verified_at = created_at;
+
+-- Each token confirms one address for one user. For an email change that is
+-- the new address, which only replaces the old one once it is confirmed.
+CREATE TABLE email_verification_tokens(
+	token_hash TEXT PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	email TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP
+);
+CREATE INDEX email_verification_tokens_user_id_idx ON email_verification_tokens (user_id);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at;